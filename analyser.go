@@ -0,0 +1,272 @@
+package nlpbench
+
+import (
+	"strings"
+)
+
+// AnalyserMode specifies the type of features extracted by an Analyser.
+type AnalyserMode int
+
+const (
+	// WordAnalyser extracts word n-grams from the tokens produced by the Tokeniser.
+	WordAnalyser AnalyserMode = iota
+
+	// CharAnalyser extracts character n-grams from the whole text (converted to lower
+	// case with runs of whitespace collapsed to a single space), so n-grams may span
+	// across words.  As the text is not split into words, stop words are not removed.
+	CharAnalyser
+
+	// CharWordBoundaryAnalyser extracts character n-grams only from text inside word
+	// boundaries.  Each word produced by the Tokeniser is padded with a space either
+	// side so that n-grams at the start and end of words are distinguished.  Any stop
+	// words are removed before n-grams are extracted.
+	CharWordBoundaryAnalyser
+)
+
+// Analyser converts raw text documents into the sequence of features (terms) counted
+// by the vectorisers.  Analyser is embedded within each of the vectorisers and so its
+// fields may be set directly on the vectoriser to configure feature extraction.
+type Analyser struct {
+	// Mode specifies whether word or character n-grams are extracted.  The zero value
+	// is WordAnalyser.
+	Mode AnalyserMode
+
+	// Tokeniser is used to split documents into word tokens.  The vectoriser
+	// constructors default to a RegExpTokeniser unless given WithTokeniser.
+	Tokeniser Tokeniser
+
+	// Normalisation specifies the Unicode normalisation form applied to documents
+	// before tokenisation.  The zero value applies no normalisation.
+	Normalisation NormalisationForm
+
+	// StripAccents removes accents and other non-spacing marks from letters before
+	// tokenisation, so for example "café" and "cafe" are counted as the same term.
+	StripAccents bool
+
+	// CaseFold applies full Unicode case folding to documents before tokenisation.  The
+	// Tokenisers only convert text to lower case which does not, for example, equate
+	// "straße" with "STRASSE".  Note that RegExpTokeniser, ScannerTokeniser and
+	// ByteTokeniser only recognise ASCII word characters and so UnicodeTokeniser should
+	// be used to retain non ASCII letters.
+	CaseFold bool
+
+	// Stemmer, if not nil, is applied to each word token after tokenisation so that
+	// inflected forms of a word are counted as a single term.  Stemming is not applied by
+	// CharAnalyser, which does not tokenise documents.
+	Stemmer Stemmer
+
+	// MinNGram and MaxNGram specify the range of n-gram lengths extracted as features
+	// e.g. MinNGram = 1 and MaxNGram = 3 will extract all unigrams, bigrams and trigrams.
+	// For WordAnalyser, lengths are measured in words and the words within each n-gram
+	// are joined by a single space.  For the character analysers, lengths are measured
+	// in characters (runes).  Values less than 1 are treated as 1 so the zero value
+	// extracts unigrams only.
+	MinNGram, MaxNGram int
+
+	// Concurrency is the number of worker goroutines used to analyse documents in
+	// parallel during Fit and Transform.  Values less than 2 process all documents
+	// sequentially on the calling goroutine.  Results are identical regardless of the
+	// level of concurrency.
+	Concurrency int
+}
+
+// AnalyserOption configures the Analyser of a vectoriser when passed to its
+// constructor.
+type AnalyserOption func(a *Analyser)
+
+// WithTokeniser returns an AnalyserOption setting the Tokeniser used to split documents
+// into word tokens, in place of the default RegExpTokeniser.
+func WithTokeniser(t Tokeniser) AnalyserOption {
+	return func(a *Analyser) {
+		a.Tokeniser = t
+	}
+}
+
+// newAnalyser returns an Analyser using the default RegExpTokeniser with opts applied.
+func newAnalyser(opts []AnalyserOption) Analyser {
+	a := Analyser{Tokeniser: NewRegExpTokeniser()}
+	for _, opt := range opts {
+		opt(&a)
+	}
+	return a
+}
+
+// analyse returns the features extracted from the supplied text after applying any
+// Unicode normalisation options (see normalise).  When extracting word n-grams longer
+// than a single word, or when stemming, any stop words (as reported by isStopWord) are
+// removed from the sequence of tokens before stemming and forming n-grams so that stop
+// words are matched against the original words and n-grams span the remaining words.
+// Otherwise, for word unigrams, tokens are returned as is and stop words are expected to
+// be excluded from the vocabulary by the caller (see checkStopWords).  For
+// CharWordBoundaryAnalyser, stop words are removed before the remaining words are
+// stemmed and split into character n-grams.  Stop words are not applied by
+// CharAnalyser, which does not split documents into words.
+func (a *Analyser) analyse(text string, isStopWord func(string) bool) []string {
+	min, max := a.nGramRange()
+	text = a.normalise(text)
+
+	switch a.Mode {
+	case CharAnalyser:
+		text = strings.Join(strings.Fields(strings.ToLower(text)), " ")
+		return charNGrams(text, min, max)
+	case CharWordBoundaryAnalyser:
+		var grams []string
+		for _, word := range a.Tokeniser.Tokenise(text) {
+			if isStopWord(word) {
+				continue
+			}
+			if a.Stemmer != nil {
+				word = a.Stemmer.Stem(word)
+			}
+			grams = charWordNGrams(" "+word+" ", min, max, grams)
+		}
+		return grams
+	}
+
+	words := a.Tokeniser.Tokenise(text)
+
+	if max == 1 && a.Stemmer == nil {
+		return words
+	}
+
+	// remove stop words before stemming and building n-grams
+	tokens := words[:0]
+	for _, word := range words {
+		if isStopWord(word) {
+			continue
+		}
+		if a.Stemmer != nil {
+			word = a.Stemmer.Stem(word)
+		}
+		tokens = append(tokens, word)
+	}
+
+	return nGrams(tokens, min, max)
+}
+
+// inPlaceTokeniser returns the Tokeniser as an InPlaceTokeniser if it supports in place
+// tokenisation and the Analyser extracts unstemmed word unigrams, so that the tokens
+// produced by the Tokeniser are the terms themselves.
+func (a *Analyser) inPlaceTokeniser() (InPlaceTokeniser, bool) {
+	t, ok := a.Tokeniser.(InPlaceTokeniser)
+	if !ok || a.Mode != WordAnalyser || a.Stemmer != nil {
+		return nil, false
+	}
+	if _, max := a.nGramRange(); max != 1 {
+		return nil, false
+	}
+	return t, true
+}
+
+// lookupTerms calls fn with the vocabulary index of each of the terms extracted from
+// text that are present in vocab.  Where possible (see inPlaceTokeniser), text is copied
+// into buf and tokenised in place so that terms are looked up in vocab without
+// allocating strings.  The (possibly grown) buffer is returned for reuse with subsequent
+// documents.
+func (a *Analyser) lookupTerms(text string, vocab map[string]int, isStopWord func(string) bool, buf []byte, fn func(i int)) []byte {
+	if t, ok := a.inPlaceTokeniser(); ok {
+		buf = append(buf[:0], a.normalise(text)...)
+		t.TokeniseBytes(buf, func(term []byte) {
+			if i, exists := vocab[string(term)]; exists {
+				fn(i)
+			}
+		})
+		return buf
+	}
+
+	for _, word := range a.analyse(text, isStopWord) {
+		if i, exists := vocab[word]; exists {
+			fn(i)
+		}
+	}
+	return buf
+}
+
+// checkStopWords returns true if the features returned by analyse may include stop
+// words that the caller should exclude from the vocabulary.  This is only the case for
+// unstemmed word unigrams where, as an optimisation, stop words need only be looked up
+// for words not already present in the vocabulary.
+func (a *Analyser) checkStopWords() bool {
+	_, max := a.nGramRange()
+	return a.Mode == WordAnalyser && max == 1 && a.Stemmer == nil
+}
+
+// nGramRange returns the effective range of n-gram lengths to extract.
+func (a *Analyser) nGramRange() (int, int) {
+	min, max := a.MinNGram, a.MaxNGram
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	return min, max
+}
+
+// nGrams returns all the n-grams of lengths min to max (inclusive) formed from
+// consecutive tokens, with the words of each n-gram joined by a single space.
+func nGrams(tokens []string, min, max int) []string {
+	if min == 1 && max == 1 {
+		return tokens
+	}
+
+	grams := make([]string, 0, len(tokens)*(max-min+1))
+
+	for i := range tokens {
+		for n := min; n <= max && i+n <= len(tokens); n++ {
+			if n == 1 {
+				grams = append(grams, tokens[i])
+				continue
+			}
+			grams = append(grams, strings.Join(tokens[i:i+n], " "))
+		}
+	}
+
+	return grams
+}
+
+// runeOffsets returns the byte offsets of the start of each rune within text followed
+// by the length of text, so that the i-th to j-th runes are text[offsets[i]:offsets[j]].
+func runeOffsets(text string) []int {
+	offsets := make([]int, 0, len(text)+1)
+	for i := range text {
+		offsets = append(offsets, i)
+	}
+	return append(offsets, len(text))
+}
+
+// charNGrams returns all the character n-grams of lengths min to max (inclusive)
+// within text.
+func charNGrams(text string, min, max int) []string {
+	offsets := runeOffsets(text)
+	runes := len(offsets) - 1
+
+	grams := make([]string, 0, runes*(max-min+1))
+
+	for i := 0; i < runes; i++ {
+		for n := min; n <= max && i+n <= runes; n++ {
+			grams = append(grams, text[offsets[i]:offsets[i+n]])
+		}
+	}
+
+	return grams
+}
+
+// charWordNGrams appends the character n-grams of lengths min to max (inclusive) within
+// the (padded) word to grams and returns the extended slice.  Words shorter than an
+// n-gram length are included whole, once only.
+func charWordNGrams(word string, min, max int, grams []string) []string {
+	offsets := runeOffsets(word)
+	runes := len(offsets) - 1
+
+	for n := min; n <= max; n++ {
+		if n >= runes {
+			return append(grams, word)
+		}
+		for i := 0; i+n <= runes; i++ {
+			grams = append(grams, word[offsets[i]:offsets[i+n]])
+		}
+	}
+
+	return grams
+}
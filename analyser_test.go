@@ -0,0 +1,285 @@
+package nlpbench
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+func TestNGrams(t *testing.T) {
+	tokens := []string{"the", "space", "shuttle", "launched"}
+
+	tests := []struct {
+		min, max int
+		want     []string
+	}{
+		{1, 1, []string{"the", "space", "shuttle", "launched"}},
+		{1, 2, []string{"the", "the space", "space", "space shuttle", "shuttle", "shuttle launched", "launched"}},
+		{2, 2, []string{"the space", "space shuttle", "shuttle launched"}},
+		{1, 3, []string{
+			"the", "the space", "the space shuttle",
+			"space", "space shuttle", "space shuttle launched",
+			"shuttle", "shuttle launched",
+			"launched",
+		}},
+		{2, 3, []string{
+			"the space", "the space shuttle",
+			"space shuttle", "space shuttle launched",
+			"shuttle launched",
+		}},
+		{4, 4, []string{"the space shuttle launched"}},
+		{5, 5, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%d-%d", test.min, test.max), func(t *testing.T) {
+			got := nGrams(tokens, test.min, test.max)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Expected %q but got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestNGramVocabulary(t *testing.T) {
+	tests := []struct {
+		name            string
+		removeStopwords bool
+		min, max        int
+		want            []string
+	}{
+		{"Unigrams", false, 1, 1, []string{"launched", "shuttle", "the", "was"}},
+		{"Bigrams", false, 2, 2, []string{"shuttle was", "the shuttle", "was launched"}},
+		{"UnigramsAndBigrams", false, 1, 2, []string{
+			"launched", "shuttle", "shuttle was", "the", "the shuttle", "was", "was launched",
+		}},
+		{"Trigrams", false, 3, 3, []string{"shuttle was launched", "the shuttle was"}},
+		// stop words are removed before n-grams are formed so n-grams span them
+		{"StopWords", true, 1, 2, []string{"launched", "shuttle", "shuttle launched"}},
+		{"StopWordsTrigrams", true, 3, 3, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vect := NewCountVectoriser2(test.removeStopwords)
+			vect.MinNGram, vect.MaxNGram = test.min, test.max
+			vect.Fit("The shuttle was launched")
+
+			got := make([]string, 0, len(vect.Vocabulary))
+			for term := range vect.Vocabulary {
+				got = append(got, term)
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Expected vocabulary %q but got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestCharNGrams(t *testing.T) {
+	tests := []struct {
+		text     string
+		min, max int
+		want     []string
+	}{
+		{"space", 1, 1, []string{"s", "p", "a", "c", "e"}},
+		{"space", 2, 3, []string{"sp", "spa", "pa", "pac", "ac", "ace", "ce"}},
+		{"a cat", 3, 3, []string{"a c", " ca", "cat"}},
+		{"café", 2, 2, []string{"ca", "af", "fé"}},
+		{"café", 3, 4, []string{"caf", "café", "afé"}},
+		{"naïve", 2, 2, []string{"na", "aï", "ïv", "ve"}},
+		{"ab", 3, 3, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s/%d-%d", test.text, test.min, test.max), func(t *testing.T) {
+			got := charNGrams(test.text, test.min, test.max)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Expected %q but got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestCharWordNGrams(t *testing.T) {
+	tests := []struct {
+		word     string
+		min, max int
+		want     []string
+	}{
+		{" cat ", 2, 2, []string{" c", "ca", "at", "t "}},
+		{" cat ", 3, 5, []string{" ca", "cat", "at ", " cat", "cat ", " cat "}},
+		{" café ", 3, 3, []string{" ca", "caf", "afé", "fé "}},
+		{" café ", 6, 6, []string{" café "}},
+		// words shorter than an n-gram length are included whole, once only
+		{" a ", 2, 5, []string{" a", "a ", " a "}},
+		{" é ", 3, 5, []string{" é "}},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%q/%d-%d", test.word, test.min, test.max), func(t *testing.T) {
+			got := charWordNGrams(test.word, test.min, test.max, nil)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Expected %q but got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestCharAnalyserVocabulary(t *testing.T) {
+	tests := []struct {
+		name            string
+		mode            AnalyserMode
+		removeStopwords bool
+		min, max        int
+		want            []string
+	}{
+		// whitespace is collapsed and n-grams span words
+		{"Char", CharAnalyser, false, 3, 3, []string{" a ", " ca", "a c", "afé", "caf", "fé ", "é a"}},
+		// stop words are not removed from the whole text
+		{"CharStopWords", CharAnalyser, true, 3, 3, []string{" a ", " ca", "a c", "afé", "caf", "fé ", "é a"}},
+		// words are padded with a space either side and n-grams do not span words
+		{"CharWordBoundary", CharWordBoundaryAnalyser, false, 3, 3, []string{" a ", " ca", "afé", "ca ", "caf", "fé "}},
+		// stop words ("a") are removed before n-grams are extracted from each word
+		{"CharWordBoundaryStopWords", CharWordBoundaryAnalyser, true, 3, 3, []string{" ca", "afé", "ca ", "caf", "fé "}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vect := NewCountVectoriser1(test.removeStopwords)
+			vect.Tokeniser = &UnicodeTokeniser{}
+			vect.Mode = test.mode
+			vect.MinNGram, vect.MaxNGram = test.min, test.max
+			vect.Fit("Café  A\tCA")
+
+			got := make([]string, 0, len(vect.Vocabulary))
+			for term := range vect.Vocabulary {
+				got = append(got, term)
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Expected vocabulary %q but got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestCharAnalyserTfidf(t *testing.T) {
+	docs := []string{"café au lait", "le café"}
+
+	for _, mode := range []struct {
+		name string
+		mode AnalyserMode
+	}{
+		{"Char", CharAnalyser},
+		{"CharWordBoundary", CharWordBoundaryAnalyser},
+	} {
+		t.Run(mode.name, func(t *testing.T) {
+			vect := NewDOKCountVectoriser1(false)
+			vect.Tokeniser = &UnicodeTokeniser{}
+			vect.Mode = mode.mode
+			vect.MinNGram, vect.MaxNGram = 2, 2
+
+			counts, err := vect.FitTransform(docs...)
+			if err != nil {
+				t.Fatalf("Failed to vectorise: %v", err)
+			}
+
+			dense, err := (&TfidfTransformer3{}).FitTransform(counts)
+			if err != nil {
+				t.Fatalf("TfidfTransformer3 failed: %v", err)
+			}
+			sparseWeights, err := (&SparseTfidfTransformer{}).FitTransform(counts)
+			if err != nil {
+				t.Fatalf("SparseTfidfTransformer failed: %v", err)
+			}
+
+			if m, n := dense.Dims(); m != len(vect.Vocabulary) || n != len(docs) {
+				t.Fatalf("Expected %dx%d matrix but got %dx%d", len(vect.Vocabulary), len(docs), m, n)
+			}
+			if !mat64.EqualApprox(dense, sparseWeights, 1e-9) {
+				t.Errorf("Expected TfidfTransformer3 and SparseTfidfTransformer to agree")
+			}
+
+			// "ca" and "au" each occur once in the first document but "ca" also occurs
+			// in the second so should be weighted lower
+			ca, au := vect.Vocabulary["ca"], vect.Vocabulary["au"]
+			if w1, w2 := dense.At(ca, 0), dense.At(au, 0); !(w1 < w2) {
+				t.Errorf("Expected tf-idf(ca) < tf-idf(au) but got %v and %v", w1, w2)
+			}
+		})
+	}
+}
+
+// Benchmark vocabulary growth and Fit/Transform cost for increasing n-gram lengths
+
+func BenchmarkNGramFit(b *testing.B) {
+	files := Load("sci.space", "sci.electronics")
+
+	for n := 1; n <= 3; n++ {
+		b.Run(fmt.Sprintf("1-%d", n), func(b *testing.B) {
+			vect := NewDOKCountVectoriser1(true)
+			vect.MinNGram, vect.MaxNGram = 1, n
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				vect.Fit(files...)
+			}
+			b.ReportMetric(float64(len(vect.Vocabulary)), "terms")
+		})
+	}
+}
+
+func BenchmarkNGramTransform(b *testing.B) {
+	files := Load("sci.space", "sci.electronics")
+
+	for n := 1; n <= 3; n++ {
+		b.Run(fmt.Sprintf("1-%d", n), func(b *testing.B) {
+			vect := NewDOKCountVectoriser1(true)
+			vect.MinNGram, vect.MaxNGram = 1, n
+			vect.Fit(files...)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				vect.Transform(files...)
+			}
+			b.ReportMetric(float64(len(vect.Vocabulary)), "terms")
+		})
+	}
+}
+
+// Benchmark character n-gram analysers against word unigrams
+func BenchmarkAnalyserModeFit(b *testing.B) {
+	files := Load("sci.space", "sci.electronics")
+
+	modes := []struct {
+		name string
+		mode AnalyserMode
+		min  int
+		max  int
+	}{
+		{"Word", WordAnalyser, 1, 1},
+		{"Char", CharAnalyser, 2, 5},
+		{"CharWB", CharWordBoundaryAnalyser, 2, 5},
+	}
+
+	for _, m := range modes {
+		b.Run(m.name, func(b *testing.B) {
+			vect := NewDOKCountVectoriser1(false)
+			vect.Mode = m.mode
+			vect.MinNGram, vect.MaxNGram = m.min, m.max
+
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				vect.Fit(files...)
+			}
+			b.ReportMetric(float64(len(vect.Vocabulary)), "terms")
+		})
+	}
+}
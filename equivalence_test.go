@@ -0,0 +1,401 @@
+package nlpbench
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/james-bowman/sparse"
+)
+
+// equivalenceTolerance is the maximum difference allowed between the elements of
+// matrices produced by different implementations of the same weighting, which may
+// legitimately accumulate floating point rounding errors in different orders.
+const equivalenceTolerance = 1e-9
+
+// fixtureCorpora are small corpora exercising the edge cases of document analysis.
+var fixtureCorpora = []struct {
+	name string
+	docs []string
+}{
+	{"Newsgroups", []string{
+		"From: fred@nasa.gov\nSubject: Re: Space Shuttle launch\n\nThe shuttle launch was delayed again due to weather.",
+		"Subject: Shuttle tiles\n\nDoes anyone know how many tiles the space shuttle has?  I heard 24,000 or so.",
+		"From: jo@mit.edu\nSubject: Op-amp circuits\n\nI'm building an op-amp circuit and the output voltage keeps drifting.",
+		"Subject: Re: Op-amp circuits\n\n> keeps drifting\nCheck the power supply voltage and the feedback resistor.",
+	}},
+	{"Punctuation", []string{
+		"Hello, world!  Hello... WORLD?",
+		"e-mail: foo@bar.com (not spam); it's 3.14 -- isn't it?",
+		"",
+	}},
+	{"Repeated", []string{
+		"shuttle shuttle shuttle",
+		"space",
+		"space shuttle launch launch space",
+	}},
+	{"Accents", []string{
+		"Café naïve résumé",
+		"café CAFÉ café cafe",
+	}},
+	{"StopWordsOnly", []string{
+		"the and of",
+		"a an the",
+	}},
+	{"Empty", []string{"", " ", "\n"}},
+}
+
+// analyserVariant configures the Analyser of a Vectoriser so that equivalence can be
+// checked across the supported forms of analysis.
+type analyserVariant struct {
+	name      string
+	configure func(a *Analyser)
+}
+
+var analyserVariants = []analyserVariant{
+	{"Default", func(a *Analyser) {}},
+	{"ByteTokeniser", func(a *Analyser) {
+		a.Tokeniser = &ByteTokeniser{}
+	}},
+	{"NGrams", func(a *Analyser) {
+		a.MinNGram, a.MaxNGram = 1, 3
+	}},
+	{"Stemmed", func(a *Analyser) {
+		a.Stemmer = &Porter2Stemmer{}
+	}},
+	{"Normalised", func(a *Analyser) {
+		a.Tokeniser = &UnicodeTokeniser{}
+		a.Normalisation = NFKC
+		a.StripAccents = true
+		a.CaseFold = true
+	}},
+	{"Char", func(a *Analyser) {
+		a.Mode = CharAnalyser
+		a.MinNGram, a.MaxNGram = 2, 3
+	}},
+	{"CharWordBoundary", func(a *Analyser) {
+		a.Mode = CharWordBoundaryAnalyser
+		a.MinNGram, a.MaxNGram = 2, 4
+	}},
+}
+
+// namedVectoriser pairs a configured Vectoriser with a name.
+type namedVectoriser struct {
+	name string
+	vect Vectoriser
+}
+
+// vocabularyVectorisers returns every Vectoriser implementation that builds a
+// vocabulary, configured with variant, including SparseCountVectoriser in each of its
+// output formats.  The HashingVectoriser is excluded as it produces a different feature
+// space.
+func vocabularyVectorisers(removeStopwords bool, variant analyserVariant) []namedVectoriser {
+	var vects []namedVectoriser
+	for _, f := range Vectorisers {
+		switch vect := f.New(removeStopwords).(type) {
+		case *HashingVectoriser:
+			continue
+		case *SparseCountVectoriser:
+			for _, format := range []struct {
+				name   string
+				format SparseFormat
+			}{{"CSR", CSRFormat}, {"CSC", CSCFormat}, {"COO", COOFormat}} {
+				vect := NewSparseCountVectoriser(removeStopwords)
+				vect.Format = format.format
+				variant.configure(analyserOf(vect))
+				vects = append(vects, namedVectoriser{f.Name + "/" + format.name, vect})
+			}
+		default:
+			variant.configure(analyserOf(vect))
+			vects = append(vects, namedVectoriser{f.Name, vect})
+		}
+	}
+	return vects
+}
+
+// hashedMatrix returns the matrix a HashingVectoriser with numFeatures features should
+// produce given the vocabulary and term document matrix of a vocabulary based
+// vectoriser, summing the counts of any terms that collide.
+func hashedMatrix(vocab map[string]int, mat mat64.Matrix, numFeatures int, alternateSign bool) *mat64.Dense {
+	_, n := mat.Dims()
+	hashed := mat64.NewDense(numFeatures, n, nil)
+	for term, i := range vocab {
+		h := hash(term)
+		row := int(h % uint32(numFeatures))
+		sign := 1.0
+		if alternateSign && h&(1<<31) != 0 {
+			sign = -1
+		}
+		for j := 0; j < n; j++ {
+			hashed.Set(row, j, hashed.At(row, j)+sign*mat.At(i, j))
+		}
+	}
+	return hashed
+}
+
+// checkVectoriserEquivalence checks that every vectoriser produces the same term
+// document matrix from docs, with CountVectoriser1 as the reference implementation,
+// and that the HashingVectoriser produces the same counts hashed into its features.
+func checkVectoriserEquivalence(t *testing.T, docs []string, removeStopwords bool, variant analyserVariant) bool {
+	ok := true
+
+	ref := NewCountVectoriser1(removeStopwords)
+	variant.configure(&ref.Analyser)
+	want, err := ref.FitTransform(docs...)
+	if err != nil {
+		t.Errorf("Reference failed to fit and transform: %v", err)
+		return false
+	}
+
+	for _, v := range vocabularyVectorisers(removeStopwords, variant) {
+		got, err := v.vect.FitTransform(docs...)
+		if err != nil {
+			t.Errorf("%s: failed to fit and transform: %v", v.name, err)
+			ok = false
+			continue
+		}
+		if !mat64.EqualApprox(got, want, equivalenceTolerance) {
+			t.Errorf("%s: expected\n%v\nbut got\n%v", v.name, mat64.Formatted(want), mat64.Formatted(got))
+			ok = false
+		}
+	}
+
+	for _, alternateSign := range []bool{false, true} {
+		vect, _ := NewHashingVectoriser(removeStopwords, 1<<10)
+		vect.AlternateSign = alternateSign
+		variant.configure(&vect.Analyser)
+
+		got, _ := vect.FitTransform(docs...)
+		hashed := hashedMatrix(ref.Vocabulary, want, vect.NumFeatures, alternateSign)
+		if !mat64.EqualApprox(got, hashed, equivalenceTolerance) {
+			t.Errorf("HashingVectoriser (AlternateSign %t): hashed counts differ from the vocabulary counts", alternateSign)
+			ok = false
+		}
+	}
+
+	return ok
+}
+
+func TestVectoriserEquivalence(t *testing.T) {
+	for _, corpus := range fixtureCorpora {
+		for _, variant := range analyserVariants {
+			for _, removeStopwords := range []bool{false, true} {
+				name := corpus.name + "/" + variant.name
+				if removeStopwords {
+					name += "/StopWords"
+				}
+				t.Run(name, func(t *testing.T) {
+					checkVectoriserEquivalence(t, corpus.docs, removeStopwords, variant)
+				})
+			}
+		}
+	}
+}
+
+// randomWords are the words from which random corpora are generated, including stop
+// words, mixed case, punctuation, digits and accented letters.
+var randomWords = strings.Fields(`the The THE a and of to in is it's space Space shuttle
+	shuttles launch launched launching orbit NASA nasa op-amp voltage circuit circuits
+	fox dog lazy quick brown jumped 3.14 24,000 e-mail foo@bar.com (not) spam; hello,
+	world! café CAFÉ naïve résumé über Straße`)
+
+// randomCorpus returns a corpus of between 1 and 20 documents, each of up to 40 words
+// drawn from randomWords with Zipfian probabilities, generated from seed.
+func randomCorpus(seed int64) []string {
+	rnd := rand.New(rand.NewSource(seed))
+	words := rand.NewZipf(rnd, termZipfS, 1, uint64(len(randomWords)-1))
+	order := rnd.Perm(len(randomWords))
+
+	docs := make([]string, 1+rnd.Intn(20))
+	for d := range docs {
+		doc := make([]string, rnd.Intn(41))
+		for w := range doc {
+			doc[w] = randomWords[order[words.Uint64()]]
+		}
+		docs[d] = strings.Join(doc, " ")
+	}
+	return docs
+}
+
+func TestVectoriserEquivalenceProperty(t *testing.T) {
+	for _, variant := range analyserVariants {
+		for _, removeStopwords := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/%t", variant.name, removeStopwords), func(t *testing.T) {
+				property := func(seed int64) bool {
+					return checkVectoriserEquivalence(t, randomCorpus(seed), removeStopwords, variant)
+				}
+				if err := quick.Check(property, &quick.Config{MaxCount: 20}); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+	}
+}
+
+// namedTransformer pairs a transformer, adapted to the SparseTransformer interface if
+// necessary, with a name.
+type namedTransformer struct {
+	name  string
+	trans SparseTransformer
+}
+
+// equivalentTransformers returns groups of transformers that should produce identical
+// results.  The first transformer of each group is the reference implementation.
+func equivalentTransformers() map[string][]namedTransformer {
+	groups := make(map[string][]namedTransformer)
+
+	idfModes := []struct {
+		name string
+		mode IdfMode
+	}{
+		{"Default", DefaultIdf},
+		{"Standard", StandardIdf},
+		{"Smooth", SmoothIdf},
+		{"Probabilistic", ProbabilisticIdf},
+		{"Max", MaxIdf},
+	}
+	for _, m := range idfModes {
+		groups["Tfidf/"+m.name] = []namedTransformer{
+			{"TfidfTransformer1", AsSparseTransformer(&TfidfTransformer1{Idf: m.mode})},
+			{"TfidfTransformer2", AsSparseTransformer(&TfidfTransformer2{Idf: m.mode})},
+			{"TfidfTransformer3", AsSparseTransformer(&TfidfTransformer3{Idf: m.mode})},
+			{"SparseTfidfTransformer", &SparseTfidfTransformer{Idf: m.mode}},
+		}
+	}
+
+	tfModes := []struct {
+		name string
+		mode TfMode
+	}{
+		{"Raw", RawTf},
+		{"Binary", BinaryTf},
+		{"Sublinear", SublinearTf},
+		{"Augmented", AugmentedTf},
+	}
+	for _, m := range tfModes {
+		groups["Tf/"+m.name] = []namedTransformer{
+			{"TfTransformer", AsSparseTransformer(NewTfTransformer(m.mode))},
+			{"SparseTfTransformer", NewSparseTfTransformer(m.mode)},
+		}
+	}
+
+	norms := []struct {
+		name string
+		norm Norm
+	}{
+		{"L2", L2Norm},
+		{"L1", L1Norm},
+		{"Max", MaxNorm},
+	}
+	for _, n := range norms {
+		groups["Norm/"+n.name] = []namedTransformer{
+			{"Normaliser", AsSparseTransformer(NewNormaliser(n.norm))},
+			{"SparseNormaliser", NewSparseNormaliser(n.norm)},
+		}
+	}
+
+	groups["BM25"] = []namedTransformer{
+		{"BM25Transformer", AsSparseTransformer(NewBM25Transformer())},
+		{"SparseBM25Transformer", NewSparseBM25Transformer()},
+	}
+
+	return groups
+}
+
+// checkTransformerEquivalence checks that every transformer within each group produces
+// the same matrix, for train and test matrices in every format, as the reference
+// implementation of the group fitted to and transforming dense matrices.
+func checkTransformerEquivalence(t *testing.T, train, test *sparse.DOK) bool {
+	ok := true
+
+	for group, transformers := range equivalentTransformers() {
+		ref := transformers[0]
+		want, err := ref.trans.Fit(train.ToDense()).Transform(test.ToDense())
+		if err != nil {
+			t.Errorf("%s/%s: failed to transform: %v", group, ref.name, err)
+			ok = false
+			continue
+		}
+
+		for _, tr := range transformers {
+			for _, f := range matrixFormats {
+				got, err := tr.trans.Fit(inFormat(train, f.format)).Transform(inFormat(test, f.format))
+				if err != nil {
+					t.Errorf("%s/%s/%s: failed to transform: %v", group, tr.name, f.name, err)
+					ok = false
+					continue
+				}
+				if !mat64.EqualApprox(got, want, equivalenceTolerance) {
+					t.Errorf("%s/%s/%s: expected\n%v\nbut got\n%v", group, tr.name, f.name, mat64.Formatted(want), mat64.Formatted(got))
+					ok = false
+				}
+			}
+		}
+	}
+
+	// the reusable and in place tf-idf transforms should match Transform
+	trans := &SparseTfidfTransformer{}
+	trans.Fit(train)
+	want, _ := trans.Transform(test.ToDense())
+	csr := test.ToCSR()
+	got, _ := trans.TransformTo(nil, csr)
+	trans.TransformInPlace(csr)
+	if !mat64.EqualApprox(got, want, equivalenceTolerance) || !mat64.EqualApprox(csr, want, equivalenceTolerance) {
+		t.Errorf("SparseTfidfTransformer: expected TransformTo and TransformInPlace to match Transform")
+		ok = false
+	}
+
+	return ok
+}
+
+// countsDOK returns the term document matrix mat, produced by a vectoriser, as a DOK.
+func countsDOK(mat mat64.Matrix) *sparse.DOK {
+	m, n := mat.Dims()
+	dok := sparse.NewDOK(m, n)
+	for i := 0; i < m; i++ {
+		for j := 0; j < n; j++ {
+			if v := mat.At(i, j); v != 0 {
+				dok.Set(i, j, v)
+			}
+		}
+	}
+	return dok
+}
+
+func TestTransformerEquivalence(t *testing.T) {
+	for _, corpus := range fixtureCorpora {
+		t.Run(corpus.name, func(t *testing.T) {
+			vect := NewCountVectoriser1(true)
+			mat, _ := vect.FitTransform(corpus.docs...)
+			counts := countsDOK(mat)
+			if m, _ := counts.Dims(); m == 0 {
+				t.Skip("corpus has no terms")
+			}
+
+			// fit to the first document only, so the test documents include terms
+			// unseen in training, and to the whole corpus
+			first, _ := vect.Transform(corpus.docs[0])
+			checkTransformerEquivalence(t, countsDOK(first), counts)
+			checkTransformerEquivalence(t, counts, counts)
+		})
+	}
+}
+
+func TestTransformerEquivalenceProperty(t *testing.T) {
+	property := func(seed int64) bool {
+		rnd := rand.New(rand.NewSource(seed))
+		m := 1 + rnd.Intn(60)
+		density := 0.01 + 0.5*rnd.Float64()
+
+		train := zipfDOK(m, 1+rnd.Intn(30), density, rnd.Int63())
+		test := zipfDOK(m, 1+rnd.Intn(30), density, rnd.Int63())
+		return checkTransformerEquivalence(t, train, test)
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 50}); err != nil {
+		t.Error(err)
+	}
+}
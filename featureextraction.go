@@ -1,330 +1,915 @@
 package nlpbench
 
 import (
+	"fmt"
+	"math"
 	"regexp"
-	"strings"
+	"sort"
 
 	"github.com/golang-collections/collections/trie"
 	"github.com/gonum/matrix/mat64"
 	"github.com/james-bowman/sparse"
 )
 
-var (
-	stopWords = []string{"a", "about", "above", "above", "across", "after", "afterwards", "again", "against", "all", "almost", "alone", "along", "already", "also", "although", "always", "am", "among", "amongst", "amoungst", "amount", "an", "and", "another", "any", "anyhow", "anyone", "anything", "anyway", "anywhere", "are", "around", "as", "at", "back", "be", "became", "because", "become", "becomes", "becoming", "been", "before", "beforehand", "behind", "being", "below", "beside", "besides", "between", "beyond", "bill", "both", "bottom", "but", "by", "call", "can", "cannot", "cant", "co", "con", "could", "couldnt", "cry", "de", "describe", "detail", "do", "done", "down", "due", "during", "each", "eg", "eight", "either", "eleven", "else", "elsewhere", "empty", "enough", "etc", "even", "ever", "every", "everyone", "everything", "everywhere", "except", "few", "fifteen", "fify", "fill", "find", "fire", "first", "five", "for", "former", "formerly", "forty", "found", "four", "from", "front", "full", "further", "get", "give", "go", "had", "has", "hasnt", "have", "he", "hence", "her", "here", "hereafter", "hereby", "herein", "hereupon", "hers", "herself", "him", "himself", "his", "how", "however", "hundred", "ie", "if", "in", "inc", "indeed", "interest", "into", "is", "it", "its", "itself", "keep", "last", "latter", "latterly", "least", "less", "ltd", "made", "many", "may", "me", "meanwhile", "might", "mill", "mine", "more", "moreover", "most", "mostly", "move", "much", "must", "my", "myself", "name", "namely", "neither", "never", "nevertheless", "next", "nine", "no", "nobody", "none", "noone", "nor", "not", "nothing", "now", "nowhere", "of", "off", "often", "on", "once", "one", "only", "onto", "or", "other", "others", "otherwise", "our", "ours", "ourselves", "out", "over", "own", "part", "per", "perhaps", "please", "put", "rather", "re", "same", "see", "seem", "seemed", "seeming", "seems", "serious", "several", "she", "should", "show", "side", "since", "sincere", "six", "sixty", "so", "some", "somehow", "someone", "something", "sometime", "sometimes", "somewhere", "still", "such", "system", "take", "ten", "than", "that", "the", "their", "them", "themselves", "then", "thence", "there", "thereafter", "thereby", "therefore", "therein", "thereupon", "these", "they", "thickv", "thin", "third", "this", "those", "though", "three", "through", "throughout", "thru", "thus", "to", "together", "too", "top", "toward", "towards", "twelve", "twenty", "two", "un", "under", "until", "up", "upon", "us", "very", "via", "was", "we", "well", "were", "what", "whatever", "when", "whence", "whenever", "where", "whereafter", "whereas", "whereby", "wherein", "whereupon", "wherever", "whether", "which", "while", "whither", "who", "whoever", "whole", "whom", "whose", "why", "will", "with", "within", "without", "would", "yet", "you", "your", "yours", "yourself", "yourselves"}
-)
+// Vectoriser is implemented by all the vectorisers within this package, each of which
+// extracts features (terms) from raw text documents and encodes them as a term document
+// matrix.  Each implementation may use a different underlying matrix type for the output
+// so the matrix is returned as a mat64.Matrix allowing implementations to be used
+// interchangeably.  Fit fits the vectoriser from scratch, discarding any previously
+// fitted vocabulary, whereas PartialFit incrementally extends the existing vocabulary
+// with new terms from the supplied documents.  Reset discards the fitted vocabulary.
+type Vectoriser interface {
+	Fit(train ...string) Vectoriser
+	PartialFit(train ...string) Vectoriser
+	Reset()
+	Transform(docs ...string) (mat64.Matrix, error)
+	FitTransform(docs ...string) (mat64.Matrix, error)
+}
 
-type CountVectoriser1 struct {
-	Vocabulary    map[string]int
-	wordTokeniser *regexp.Regexp
-	stopWords     *regexp.Regexp
+// VectoriserFactory pairs a Vectoriser implementation with a name and a constructor
+// function so that benchmarks may iterate over every implementation generically.
+type VectoriserFactory struct {
+	Name string
+	New  func(removeStopwords bool) Vectoriser
+}
+
+// Vectorisers is a registry of every Vectoriser implementation in the package.
+var Vectorisers = []VectoriserFactory{
+	{"CountVectoriser1", func(removeStopwords bool) Vectoriser { return NewCountVectoriser1(removeStopwords) }},
+	{"CountVectoriser2", func(removeStopwords bool) Vectoriser { return NewCountVectoriser2(removeStopwords) }},
+	{"CountVectoriser3", func(removeStopwords bool) Vectoriser { return NewCountVectoriser3(removeStopwords) }},
+	{"CountVectoriser4", func(removeStopwords bool) Vectoriser { return NewCountVectoriser4(removeStopwords) }},
+	{"CountVectoriser5", func(removeStopwords bool) Vectoriser { return NewCountVectoriser5(removeStopwords) }},
+	{"CountVectoriser6", func(removeStopwords bool) Vectoriser { return NewCountVectoriser6(removeStopwords) }},
+	{"CountVectoriser7", func(removeStopwords bool) Vectoriser { return NewCountVectoriser7(removeStopwords) }},
+	{"DOKCountVectoriser1", func(removeStopwords bool) Vectoriser { return NewDOKCountVectoriser1(removeStopwords) }},
+	{"SparseCountVectoriser", func(removeStopwords bool) Vectoriser { return NewSparseCountVectoriser(removeStopwords) }},
+	{"HashingVectoriser", func(removeStopwords bool) Vectoriser {
+		// DefaultNumFeatures is always valid so no error can be returned
+		v, _ := NewHashingVectoriser(removeStopwords, DefaultNumFeatures)
+		return v
+	}},
 }
 
-func NewCountVectoriser1(removeStopwords bool) *CountVectoriser1 {
-	var stop *regexp.Regexp
+// newRegExpStopWords compiles the stop words into a single regular expression matching
+// any of the words.  nil is returned if there are no stop words.
+func newRegExpStopWords(words []string) *regexp.Regexp {
+	if len(words) == 0 {
+		return nil
+	}
 
-	if removeStopwords {
-		reStr := "\\A("
+	reStr := "\\A("
 
-		for i, word := range stopWords {
-			if i != 0 {
-				reStr += `|`
-			}
-			reStr += `\Q` + word + `\E`
+	for i, word := range words {
+		if i != 0 {
+			reStr += `|`
 		}
-		reStr += ")\\z"
-		stop = regexp.MustCompile(reStr)
+		reStr += regexp.QuoteMeta(word)
 	}
-	return &CountVectoriser1{
-		Vocabulary:    make(map[string]int),
-		wordTokeniser: regexp.MustCompile("\\w+"),
-		stopWords:     stop,
+	reStr += ")\\z"
+
+	return regexp.MustCompile(reStr)
+}
+
+// newMapStopWords builds a map of the stop words for lookups.  nil is returned if there
+// are no stop words.
+func newMapStopWords(words []string) map[string]bool {
+	if len(words) == 0 {
+		return nil
 	}
+
+	stop := make(map[string]bool)
+	for _, word := range words {
+		stop[word] = true
+	}
+
+	return stop
 }
 
-func (v *CountVectoriser1) Fit(train ...string) *CountVectoriser1 {
-	i := 0
-	for _, doc := range train {
-		words := v.tokenise(doc)
+// newTrieStopWords inserts the stop words into a trie for lookups.  nil is returned if
+// there are no stop words.
+func newTrieStopWords(words []string) *trie.Trie {
+	if len(words) == 0 {
+		return nil
+	}
 
-		for _, word := range words {
-			_, exists := v.Vocabulary[word]
-			if !exists {
-				// if enabled, remove stop words
-				if v.stopWords != nil {
-					if v.stopWords.MatchString(word) {
-						continue
-					}
-				}
-				v.Vocabulary[word] = i
-				i++
-			}
-		}
+	stop := trie.New()
+	for _, word := range words {
+		stop.Insert(word, true)
 	}
 
-	return v
+	return stop
 }
 
-func (v *CountVectoriser1) Transform(docs ...string) (*mat64.Dense, error) {
-	mat := mat64.NewDense(len(v.Vocabulary), len(docs), nil)
+// vocabularyVectoriser holds the vocabulary and stop words shared by the count
+// vectorisers, along with the logic to fit and reset the vocabulary.  Each vectoriser
+// embeds vocabularyVectoriser and provides its own Transform along with Fit and
+// PartialFit methods returning the embedding vectoriser.
+type vocabularyVectoriser struct {
+	Analyser
+	VocabularyLimits
+	Vocabulary   map[string]int
+	lookup       stopWordLookupType
+	stopWords    stopWordSet
+	stopWordList []string
+}
 
-	for d, doc := range docs {
-		words := v.tokenise(doc)
+// newVocabularyVectoriser constructs a vocabularyVectoriser looking up stop words using
+// the data structure identified by lookup.  If removeStopwords is true the English stop
+// words are removed.
+func newVocabularyVectoriser(lookup stopWordLookupType, removeStopwords bool, opts []AnalyserOption) vocabularyVectoriser {
+	v := vocabularyVectoriser{
+		Analyser:   newAnalyser(opts),
+		Vocabulary: make(map[string]int),
+		lookup:     lookup,
+	}
 
-		for _, word := range words {
-			i, exists := v.Vocabulary[word]
+	if removeStopwords {
+		v.SetStopWords(EnglishStopWords)
+	}
+	return v
+}
 
-			if exists {
-				mat.Set(i, d, mat.At(i, d)+1)
-			}
-		}
+// fit discards any existing vocabulary and fits the vectoriser to the training data,
+// pruning the resulting vocabulary according to the VocabularyLimits.
+func (v *vocabularyVectoriser) fit(train []string) {
+	v.Reset()
+	if !v.VocabularyLimits.enabled() {
+		v.partialFit(train)
+		return
 	}
-	return mat, nil
+
+	freqs := &termFrequencies{}
+	v.fitVocabulary(v.Vocabulary, train, v.isStopWord, freqs)
+	v.Vocabulary = v.prune(v.Vocabulary, freqs, len(train))
 }
 
-func (v *CountVectoriser1) FitTransform(docs ...string) (*mat64.Dense, error) {
-	return v.Fit(docs...).Transform(docs...)
+// partialFit extends the existing vocabulary with any new terms found in the training
+// data.  New terms are assigned indices following on from those already present.
+func (v *vocabularyVectoriser) partialFit(train []string) {
+	v.fitVocabulary(v.Vocabulary, train, v.isStopWord, nil)
 }
 
-func (v *CountVectoriser1) tokenise(text string) []string {
-	// convert content to lower case
-	c := strings.ToLower(text)
+// fitStream discards any existing vocabulary and fits the vectoriser to the training
+// documents read from src, pruning the resulting vocabulary according to the
+// VocabularyLimits.
+func (v *vocabularyVectoriser) fitStream(src DocumentSource) error {
+	v.Reset()
 
-	// match whole words, removing any punctuation/whitespace
-	words := v.wordTokeniser.FindAllString(c, -1)
+	var freqs *termFrequencies
+	if v.VocabularyLimits.enabled() {
+		freqs = &termFrequencies{}
+	}
 
-	return words
+	n, err := v.fitVocabularyStream(v.Vocabulary, src, v.isStopWord, freqs)
+	if err != nil {
+		return err
+	}
+	if freqs != nil {
+		v.Vocabulary = v.prune(v.Vocabulary, freqs, n)
+	}
+	return nil
 }
 
-type CountVectoriser2 struct {
-	Vocabulary    map[string]int
-	wordTokeniser *regexp.Regexp
-	stopWords     map[string]bool
+// Reset discards the fitted vocabulary.
+func (v *vocabularyVectoriser) Reset() {
+	v.Vocabulary = make(map[string]int)
 }
 
-func NewCountVectoriser2(removeStopwords bool) *CountVectoriser2 {
-	var stop map[string]bool
+// isStopWord returns true if word is present in the stop word set.  A nil set (stop
+// word removal disabled) contains no words.
+func (v *vocabularyVectoriser) isStopWord(word string) bool {
+	return v.stopWords != nil && v.stopWords.has(word)
+}
 
-	if removeStopwords {
-		stop = make(map[string]bool)
-		for _, word := range stopWords {
-			stop[word] = true
-		}
-	}
-	return &CountVectoriser2{
-		Vocabulary:    make(map[string]int),
-		wordTokeniser: regexp.MustCompile("\\w+"),
-		stopWords:     stop,
-	}
+// SetStopWords sets the list of stop words to remove, building the vectoriser's stop
+// word lookup.  An empty list disables stop word removal.
+func (v *vocabularyVectoriser) SetStopWords(words []string) {
+	v.stopWordList = uniqueStopWords(words)
+	v.stopWords = v.lookup.build(v.stopWordList)
 }
 
-func (v *CountVectoriser2) Fit(train ...string) *CountVectoriser2 {
-	i := 0
-	for _, doc := range train {
-		words := v.tokenise(doc)
+// countColumns counts the terms within each of the documents into shards of compressed
+// sparse columns.  If sorted is true, the row indices within each column are ordered.
+func (v *vocabularyVectoriser) countColumns(docs []string, sorted bool) []*compressedColumns {
+	m := len(v.Vocabulary)
 
-		for _, word := range words {
-			_, exists := v.Vocabulary[word]
-			if !exists {
-				// if enabled, remove stop words
-				if v.stopWords != nil {
-					if v.stopWords[word] {
-						continue
-					}
-				}
-				v.Vocabulary[word] = i
-				i++
+	// each shard of documents is built as a separate set of columns so shards may be
+	// processed concurrently and then joined
+	shards := make([]*compressedColumns, v.shards(len(docs)))
+
+	v.parallel(len(docs), func(shard, start, end int) {
+		cols := newCompressedColumns(end - start)
+
+		// dense accumulator of term counts for the current document, reset after each
+		// document by visiting only the terms that occurred
+		counts := make([]float64, m)
+		count := func(i int) {
+			if counts[i] == 0 {
+				cols.ind = append(cols.ind, i)
 			}
+			counts[i]++
 		}
-	}
 
-	return v
+		var buf []byte
+		for d := start; d < end; d++ {
+			col := len(cols.ind)
+			buf = v.lookupTerms(docs[d], v.Vocabulary, v.isStopWord, buf, count)
+
+			if sorted {
+				sort.Ints(cols.ind[col:])
+			}
+			for _, i := range cols.ind[col:] {
+				cols.data = append(cols.data, counts[i])
+				counts[i] = 0
+			}
+			cols.endColumn()
+		}
+		shards[shard] = cols
+	})
+
+	return shards
 }
 
-func (v *CountVectoriser2) Transform(docs ...string) (*mat64.Dense, error) {
-	mat := mat64.NewDense(len(v.Vocabulary), len(docs), nil)
+// countVectoriser is the implementation shared by CountVectoriser1 to CountVectoriser7,
+// which differ only in the data structure used to look up stop words, vectorising
+// documents into a dense term document matrix.
+type countVectoriser struct {
+	vocabularyVectoriser
+}
 
-	for d, doc := range docs {
-		words := v.tokenise(doc)
+func newCountVectoriser(lookup stopWordLookupType, removeStopwords bool, opts []AnalyserOption) countVectoriser {
+	return countVectoriser{newVocabularyVectoriser(lookup, removeStopwords, opts)}
+}
 
-		for _, word := range words {
-			i, exists := v.Vocabulary[word]
+func (v *countVectoriser) Transform(docs ...string) (mat64.Matrix, error) {
+	mat := mat64.NewDense(len(v.Vocabulary), len(docs), nil)
 
-			if exists {
+	// each shard of documents populates a separate range of columns so shards may
+	// safely be processed concurrently
+	v.parallel(len(docs), func(shard, start, end int) {
+		var buf []byte
+		for d := start; d < end; d++ {
+			buf = v.lookupTerms(docs[d], v.Vocabulary, v.isStopWord, buf, func(i int) {
 				mat.Set(i, d, mat.At(i, d)+1)
-			}
+			})
 		}
-	}
+	})
 	return mat, nil
 }
 
-func (v *CountVectoriser2) FitTransform(docs ...string) (*mat64.Dense, error) {
-	return v.Fit(docs...).Transform(docs...)
+func (v *countVectoriser) FitTransform(docs ...string) (mat64.Matrix, error) {
+	v.fit(docs)
+	return v.Transform(docs...)
 }
 
-func (v *CountVectoriser2) tokenise(text string) []string {
-	// convert content to lower case
-	c := strings.ToLower(text)
+// CountVectoriser1 is a CountVectoriser that removes stop words using a single regular
+// expression matching any of the stop words.
+type CountVectoriser1 struct {
+	countVectoriser
+}
+
+func NewCountVectoriser1(removeStopwords bool, opts ...AnalyserOption) *CountVectoriser1 {
+	return &CountVectoriser1{newCountVectoriser(regExpLookup, removeStopwords, opts)}
+}
+
+// Fit discards any existing vocabulary and fits the vectoriser to the training data,
+// pruning the resulting vocabulary according to the VocabularyLimits.
+func (v *CountVectoriser1) Fit(train ...string) Vectoriser {
+	v.fit(train)
+	return v
+}
+
+// PartialFit extends the existing vocabulary with any new terms found in the training
+// data.  New terms are assigned indices following on from those already present.
+func (v *CountVectoriser1) PartialFit(train ...string) Vectoriser {
+	v.partialFit(train)
+	return v
+}
+
+// FitStream discards any existing vocabulary and fits the vectoriser to the training
+// documents read from src, pruning the resulting vocabulary according to the
+// VocabularyLimits.  Documents are read and processed in batches so only the vocabulary
+// is retained in memory.
+func (v *CountVectoriser1) FitStream(src DocumentSource) (Vectoriser, error) {
+	err := v.fitStream(src)
+	return v, err
+}
+
+// CountVectoriser2 is a CountVectoriser that removes stop words using a map.
+type CountVectoriser2 struct {
+	countVectoriser
+}
 
-	// match whole words, removing any punctuation/whitespace
-	words := v.wordTokeniser.FindAllString(c, -1)
+func NewCountVectoriser2(removeStopwords bool, opts ...AnalyserOption) *CountVectoriser2 {
+	return &CountVectoriser2{newCountVectoriser(mapLookup, removeStopwords, opts)}
+}
+
+// Fit discards any existing vocabulary and fits the vectoriser to the training data,
+// pruning the resulting vocabulary according to the VocabularyLimits.
+func (v *CountVectoriser2) Fit(train ...string) Vectoriser {
+	v.fit(train)
+	return v
+}
 
-	return words
+// PartialFit extends the existing vocabulary with any new terms found in the training
+// data.  New terms are assigned indices following on from those already present.
+func (v *CountVectoriser2) PartialFit(train ...string) Vectoriser {
+	v.partialFit(train)
+	return v
+}
+
+// FitStream discards any existing vocabulary and fits the vectoriser to the training
+// documents read from src, pruning the resulting vocabulary according to the
+// VocabularyLimits.  Documents are read and processed in batches so only the vocabulary
+// is retained in memory.
+func (v *CountVectoriser2) FitStream(src DocumentSource) (Vectoriser, error) {
+	err := v.fitStream(src)
+	return v, err
 }
 
+// CountVectoriser3 is a CountVectoriser that removes stop words using a trie.
 type CountVectoriser3 struct {
-	Vocabulary    map[string]int
-	wordTokeniser *regexp.Regexp
-	stopWords     *trie.Trie
+	countVectoriser
 }
 
-func NewCountVectoriser3(removeStopwords bool) *CountVectoriser3 {
-	var stop *trie.Trie
+func NewCountVectoriser3(removeStopwords bool, opts ...AnalyserOption) *CountVectoriser3 {
+	return &CountVectoriser3{newCountVectoriser(trieLookup, removeStopwords, opts)}
+}
 
-	if removeStopwords {
-		stop = trie.New()
-		for _, word := range stopWords {
-			stop.Insert(word, true)
-		}
+// Fit discards any existing vocabulary and fits the vectoriser to the training data,
+// pruning the resulting vocabulary according to the VocabularyLimits.
+func (v *CountVectoriser3) Fit(train ...string) Vectoriser {
+	v.fit(train)
+	return v
+}
+
+// PartialFit extends the existing vocabulary with any new terms found in the training
+// data.  New terms are assigned indices following on from those already present.
+func (v *CountVectoriser3) PartialFit(train ...string) Vectoriser {
+	v.partialFit(train)
+	return v
+}
+
+// FitStream discards any existing vocabulary and fits the vectoriser to the training
+// documents read from src, pruning the resulting vocabulary according to the
+// VocabularyLimits.  Documents are read and processed in batches so only the vocabulary
+// is retained in memory.
+func (v *CountVectoriser3) FitStream(src DocumentSource) (Vectoriser, error) {
+	err := v.fitStream(src)
+	return v, err
+}
+
+// CountVectoriser4 is a CountVectoriser that removes stop words using a minimal perfect
+// hash table.
+type CountVectoriser4 struct {
+	countVectoriser
+}
+
+func NewCountVectoriser4(removeStopwords bool, opts ...AnalyserOption) *CountVectoriser4 {
+	return &CountVectoriser4{newCountVectoriser(perfectHashLookup, removeStopwords, opts)}
+}
+
+// Fit discards any existing vocabulary and fits the vectoriser to the training data,
+// pruning the resulting vocabulary according to the VocabularyLimits.
+func (v *CountVectoriser4) Fit(train ...string) Vectoriser {
+	v.fit(train)
+	return v
+}
+
+// PartialFit extends the existing vocabulary with any new terms found in the training
+// data.  New terms are assigned indices following on from those already present.
+func (v *CountVectoriser4) PartialFit(train ...string) Vectoriser {
+	v.partialFit(train)
+	return v
+}
+
+// FitStream discards any existing vocabulary and fits the vectoriser to the training
+// documents read from src, pruning the resulting vocabulary according to the
+// VocabularyLimits.  Documents are read and processed in batches so only the vocabulary
+// is retained in memory.
+func (v *CountVectoriser4) FitStream(src DocumentSource) (Vectoriser, error) {
+	err := v.fitStream(src)
+	return v, err
+}
+
+// CountVectoriser5 is a CountVectoriser that removes stop words using binary search
+// over a sorted slice.
+type CountVectoriser5 struct {
+	countVectoriser
+}
+
+func NewCountVectoriser5(removeStopwords bool, opts ...AnalyserOption) *CountVectoriser5 {
+	return &CountVectoriser5{newCountVectoriser(sortedLookup, removeStopwords, opts)}
+}
+
+// Fit discards any existing vocabulary and fits the vectoriser to the training data,
+// pruning the resulting vocabulary according to the VocabularyLimits.
+func (v *CountVectoriser5) Fit(train ...string) Vectoriser {
+	v.fit(train)
+	return v
+}
+
+// PartialFit extends the existing vocabulary with any new terms found in the training
+// data.  New terms are assigned indices following on from those already present.
+func (v *CountVectoriser5) PartialFit(train ...string) Vectoriser {
+	v.partialFit(train)
+	return v
+}
+
+// FitStream discards any existing vocabulary and fits the vectoriser to the training
+// documents read from src, pruning the resulting vocabulary according to the
+// VocabularyLimits.  Documents are read and processed in batches so only the vocabulary
+// is retained in memory.
+func (v *CountVectoriser5) FitStream(src DocumentSource) (Vectoriser, error) {
+	err := v.fitStream(src)
+	return v, err
+}
+
+// CountVectoriser6 is a CountVectoriser that removes stop words using a length bucketed
+// lookup equivalent to a switch statement over the stop words.
+type CountVectoriser6 struct {
+	countVectoriser
+}
+
+func NewCountVectoriser6(removeStopwords bool, opts ...AnalyserOption) *CountVectoriser6 {
+	return &CountVectoriser6{newCountVectoriser(lengthBucketedLookup, removeStopwords, opts)}
+}
+
+// Fit discards any existing vocabulary and fits the vectoriser to the training data,
+// pruning the resulting vocabulary according to the VocabularyLimits.
+func (v *CountVectoriser6) Fit(train ...string) Vectoriser {
+	v.fit(train)
+	return v
+}
+
+// PartialFit extends the existing vocabulary with any new terms found in the training
+// data.  New terms are assigned indices following on from those already present.
+func (v *CountVectoriser6) PartialFit(train ...string) Vectoriser {
+	v.partialFit(train)
+	return v
+}
+
+// FitStream discards any existing vocabulary and fits the vectoriser to the training
+// documents read from src, pruning the resulting vocabulary according to the
+// VocabularyLimits.  Documents are read and processed in batches so only the vocabulary
+// is retained in memory.
+func (v *CountVectoriser6) FitStream(src DocumentSource) (Vectoriser, error) {
+	err := v.fitStream(src)
+	return v, err
+}
+
+// CountVectoriser7 is a CountVectoriser that removes stop words using a bloom filter
+// to prefilter lookups into a map.
+type CountVectoriser7 struct {
+	countVectoriser
+}
+
+func NewCountVectoriser7(removeStopwords bool, opts ...AnalyserOption) *CountVectoriser7 {
+	return &CountVectoriser7{newCountVectoriser(bloomLookup, removeStopwords, opts)}
+}
+
+// Fit discards any existing vocabulary and fits the vectoriser to the training data,
+// pruning the resulting vocabulary according to the VocabularyLimits.
+func (v *CountVectoriser7) Fit(train ...string) Vectoriser {
+	v.fit(train)
+	return v
+}
+
+// PartialFit extends the existing vocabulary with any new terms found in the training
+// data.  New terms are assigned indices following on from those already present.
+func (v *CountVectoriser7) PartialFit(train ...string) Vectoriser {
+	v.partialFit(train)
+	return v
+}
+
+// FitStream discards any existing vocabulary and fits the vectoriser to the training
+// documents read from src, pruning the resulting vocabulary according to the
+// VocabularyLimits.  Documents are read and processed in batches so only the vocabulary
+// is retained in memory.
+func (v *CountVectoriser7) FitStream(src DocumentSource) (Vectoriser, error) {
+	err := v.fitStream(src)
+	return v, err
+}
+
+// DOKCountVectoriser1 is a count vectoriser that removes stop words using a regular
+// expression and outputs a *sparse.DOK term document matrix.
+type DOKCountVectoriser1 struct {
+	vocabularyVectoriser
+}
+
+func NewDOKCountVectoriser1(removeStopwords bool, opts ...AnalyserOption) *DOKCountVectoriser1 {
+	return &DOKCountVectoriser1{newVocabularyVectoriser(regExpLookup, removeStopwords, opts)}
+}
+
+// Fit discards any existing vocabulary and fits the vectoriser to the training data,
+// pruning the resulting vocabulary according to the VocabularyLimits.
+func (v *DOKCountVectoriser1) Fit(train ...string) Vectoriser {
+	v.fit(train)
+	return v
+}
+
+// PartialFit extends the existing vocabulary with any new terms found in the training
+// data.  New terms are assigned indices following on from those already present.
+func (v *DOKCountVectoriser1) PartialFit(train ...string) Vectoriser {
+	v.partialFit(train)
+	return v
+}
+
+func (v *DOKCountVectoriser1) Transform(docs ...string) (mat64.Matrix, error) {
+	if v.shards(len(docs)) > 1 {
+		// the DOK is not safe for concurrent use so count the terms within the
+		// documents concurrently and then populate the matrix sequentially
+		return columnsToDOK(len(v.Vocabulary), v.countColumns(docs, false)), nil
 	}
-	return &CountVectoriser3{
-		Vocabulary:    make(map[string]int),
-		wordTokeniser: regexp.MustCompile("\\w+"),
-		stopWords:     stop,
+
+	mat := sparse.NewDOK(len(v.Vocabulary), len(docs))
+
+	var buf []byte
+	for d, doc := range docs {
+		buf = v.lookupTerms(doc, v.Vocabulary, v.isStopWord, buf, func(i int) {
+			mat.Set(i, d, mat.At(i, d)+1)
+		})
 	}
+	return mat, nil
 }
 
-func (v *CountVectoriser3) Fit(train ...string) *CountVectoriser3 {
-	i := 0
-	for _, doc := range train {
-		words := v.tokenise(doc)
+func (v *DOKCountVectoriser1) FitTransform(docs ...string) (mat64.Matrix, error) {
+	return v.Fit(docs...).Transform(docs...)
+}
 
-		for _, word := range words {
-			_, exists := v.Vocabulary[word]
-			if !exists {
-				// if enabled, remove stop words
-				if v.stopWords != nil {
-					if v.stopWords.Has(word) {
-						continue
-					}
-				}
-				v.Vocabulary[word] = i
-				i++
+// FitStream discards any existing vocabulary and fits the vectoriser to the training
+// documents read from src, pruning the resulting vocabulary according to the
+// VocabularyLimits.  Documents are read and processed in batches so only the vocabulary
+// is retained in memory.
+func (v *DOKCountVectoriser1) FitStream(src DocumentSource) (Vectoriser, error) {
+	err := v.fitStream(src)
+	return v, err
+}
+
+// TransformStream vectorises the documents read from src into a term document matrix
+// returned as a *sparse.DOK.  Documents are read and processed in batches.  As the
+// number of columns of the DOK is not known until all the documents have been read, the
+// term counts of each batch are held as compressed sparse columns until then.  Memory
+// use therefore grows with the number of non-zero elements in the output matrix, as for
+// SparseCountVectoriser, rather than with the size of the documents.
+func (v *DOKCountVectoriser1) TransformStream(src DocumentSource) (mat64.Matrix, error) {
+	shards, err := streamColumns(src, func(docs []string) []*compressedColumns {
+		return v.countColumns(docs, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return columnsToDOK(len(v.Vocabulary), shards), nil
+}
+
+// columnsToDOK builds an m row DOK matrix from the shards of compressed sparse columns,
+// with the columns of each shard following on from those of the previous shard.
+func columnsToDOK(m int, shards []*compressedColumns) *sparse.DOK {
+	var n int
+	for _, cols := range shards {
+		n += len(cols.indptr) - 1
+	}
+
+	mat := sparse.NewDOK(m, n)
+	var d int
+	for _, cols := range shards {
+		for j := 0; j < len(cols.indptr)-1; j, d = j+1, d+1 {
+			for k := cols.indptr[j]; k < cols.indptr[j+1]; k++ {
+				mat.Set(cols.ind[k], d, cols.data[k])
 			}
 		}
 	}
+	return mat
+}
+
+// SparseFormat specifies the sparse matrix format output by a SparseCountVectoriser.
+type SparseFormat int
+
+const (
+	// CSRFormat outputs a *sparse.CSR (Compressed Sparse Row) matrix.
+	CSRFormat SparseFormat = iota
+
+	// CSCFormat outputs a *sparse.CSC (Compressed Sparse Column) matrix.
+	CSCFormat
+
+	// COOFormat outputs a *sparse.COO (COOrdinate) matrix.
+	COOFormat
+)
 
+// SparseCountVectoriser is a count vectoriser that assembles the arrays of the output
+// sparse matrix directly, rather than building a sparse.DOK matrix and then converting
+// it to the desired format as with DOKCountVectoriser1.  Term counts are accumulated
+// per document (column) so the matrix is naturally built in CSC form and then, if
+// required, converted to CSR or COO with a single pass over the arrays.
+type SparseCountVectoriser struct {
+	vocabularyVectoriser
+
+	// Format is the sparse matrix format output by Transform.  The zero value is
+	// CSRFormat.
+	Format SparseFormat
+}
+
+// NewSparseCountVectoriser constructs a new SparseCountVectoriser outputting CSR
+// matrices.
+func NewSparseCountVectoriser(removeStopwords bool, opts ...AnalyserOption) *SparseCountVectoriser {
+	return &SparseCountVectoriser{vocabularyVectoriser: newVocabularyVectoriser(mapLookup, removeStopwords, opts)}
+}
+
+// Fit discards any existing vocabulary and fits the vectoriser to the training data,
+// pruning the resulting vocabulary according to the VocabularyLimits.
+func (v *SparseCountVectoriser) Fit(train ...string) Vectoriser {
+	v.fit(train)
 	return v
 }
 
-func (v *CountVectoriser3) Transform(docs ...string) (*mat64.Dense, error) {
-	mat := mat64.NewDense(len(v.Vocabulary), len(docs), nil)
+// PartialFit extends the existing vocabulary with any new terms found in the training
+// data.  New terms are assigned indices following on from those already present.
+func (v *SparseCountVectoriser) PartialFit(train ...string) Vectoriser {
+	v.partialFit(train)
+	return v
+}
 
-	for d, doc := range docs {
-		words := v.tokenise(doc)
+// Transform vectorises the documents into a term document matrix in the sparse format
+// specified by Format.
+func (v *SparseCountVectoriser) Transform(docs ...string) (mat64.Matrix, error) {
+	return v.assemble(v.transformColumns(docs)), nil
+}
 
-		for _, word := range words {
-			i, exists := v.Vocabulary[word]
+// FitStream discards any existing vocabulary and fits the vectoriser to the training
+// documents read from src, pruning the resulting vocabulary according to the
+// VocabularyLimits.  Documents are read and processed in batches so only the vocabulary
+// is retained in memory.
+func (v *SparseCountVectoriser) FitStream(src DocumentSource) (Vectoriser, error) {
+	err := v.fitStream(src)
+	return v, err
+}
 
-			if exists {
-				mat.Set(i, d, mat.At(i, d)+1)
+// TransformStream vectorises the documents read from src into a term document matrix in
+// the sparse format specified by Format.  Documents are read and processed in batches
+// so only the (sparse) output matrix is retained in memory.
+func (v *SparseCountVectoriser) TransformStream(src DocumentSource) (mat64.Matrix, error) {
+	shards, err := streamColumns(src, v.transformColumns)
+	if err != nil {
+		return nil, err
+	}
+	return v.assemble(shards), nil
+}
+
+// transformColumns vectorises the documents into shards of compressed sparse columns,
+// ordering the row indices within each column if Format is CSCFormat.
+func (v *SparseCountVectoriser) transformColumns(docs []string) []*compressedColumns {
+	return v.countColumns(docs, v.Format == CSCFormat)
+}
+
+// assemble joins the shards of compressed sparse columns into a single matrix in the
+// sparse format specified by Format.
+func (v *SparseCountVectoriser) assemble(shards []*compressedColumns) mat64.Matrix {
+	m := len(v.Vocabulary)
+	indptr, ind, data := concatColumns(shards)
+	n := len(indptr) - 1
+
+	switch v.Format {
+	case CSCFormat:
+		return sparse.NewCSC(m, n, indptr, ind, data)
+	case COOFormat:
+		cols := make([]int, len(ind))
+		for j := 0; j < n; j++ {
+			for k := indptr[j]; k < indptr[j+1]; k++ {
+				cols[k] = j
 			}
 		}
+		return sparse.NewCOO(m, n, ind, cols, data)
 	}
-	return mat, nil
+
+	ia, ja, vals := transposeCompressed(m, indptr, ind, data)
+	return sparse.NewCSR(m, n, ia, ja, vals)
 }
 
-func (v *CountVectoriser3) FitTransform(docs ...string) (*mat64.Dense, error) {
+func (v *SparseCountVectoriser) FitTransform(docs ...string) (mat64.Matrix, error) {
 	return v.Fit(docs...).Transform(docs...)
 }
 
-func (v *CountVectoriser3) tokenise(text string) []string {
-	// convert content to lower case
-	c := strings.ToLower(text)
+// HashingVectoriser vectorises documents without storing a vocabulary.  Instead of
+// assigning each term a row index in a Vocabulary map, each term is hashed to one of a
+// fixed number of feature rows using a fast non-cryptographic hash (FNV-1a).  This
+// keeps memory use constant regardless of corpus size and allows independent processes
+// to vectorise documents consistently without sharing a vocabulary, at the cost of
+// possible hash collisions between terms and the inability to map rows back to terms.
+type HashingVectoriser struct {
+	Analyser
+
+	// NumFeatures is the number of rows in the output term document matrix i.e. the
+	// number of distinct hash buckets terms are mapped into.
+	NumFeatures int
+
+	// AlternateSign, when true, uses a bit of the hash to determine the sign of the
+	// value added for each term so that colliding terms tend to cancel each other out
+	// rather than accumulating, reducing the bias introduced by collisions.
+	AlternateSign bool
+
+	stopWords    map[string]bool
+	stopWordList []string
+}
+
+// DefaultNumFeatures is the number of features of the HashingVectoriser in the
+// Vectorisers registry.  It is large enough to keep collisions rare for the vocabulary of
+// the 20 newsgroups corpus while keeping the row index of the CSR output, which has an
+// entry per feature, small.
+const DefaultNumFeatures = 1 << 18
+
+// NewHashingVectoriser constructs a new HashingVectoriser producing term document
+// matrices with numFeatures rows.  An error is returned if numFeatures is not between 1
+// and 2^32 - 1 (the range of the hash).
+func NewHashingVectoriser(removeStopwords bool, numFeatures int, opts ...AnalyserOption) (*HashingVectoriser, error) {
+	if err := checkNumFeatures(numFeatures); err != nil {
+		return nil, err
+	}
+
+	v := &HashingVectoriser{
+		Analyser:    newAnalyser(opts),
+		NumFeatures: numFeatures,
+	}
 
-	// match whole words, removing any punctuation/whitespace
-	words := v.wordTokeniser.FindAllString(c, -1)
+	if removeStopwords {
+		v.SetStopWords(EnglishStopWords)
+	}
+	return v, nil
+}
 
-	return words
+// checkNumFeatures returns an error if numFeatures is outside the range of hash buckets
+// that terms may be hashed into.
+func checkNumFeatures(numFeatures int) error {
+	if numFeatures <= 0 || uint64(numFeatures) > math.MaxUint32 {
+		return fmt.Errorf("nlpbench: NumFeatures must be between 1 and %d but was %d", uint64(math.MaxUint32), numFeatures)
+	}
+	return nil
 }
 
-type DOKCountVectoriser1 struct {
-	Vocabulary    map[string]int
-	wordTokeniser *regexp.Regexp
-	stopWords     *regexp.Regexp
+// Fit does nothing as the HashingVectoriser is stateless and requires no training.  It
+// is provided so that HashingVectoriser satisfies the Vectoriser interface.
+func (v *HashingVectoriser) Fit(train ...string) Vectoriser {
+	return v
 }
 
-func NewDOKCountVectoriser1(removeStopwords bool) *DOKCountVectoriser1 {
-	var stop *regexp.Regexp
+// PartialFit does nothing as the HashingVectoriser is stateless and requires no
+// training.
+func (v *HashingVectoriser) PartialFit(train ...string) Vectoriser {
+	return v
+}
 
-	if removeStopwords {
-		reStr := "\\A("
+// Reset does nothing as the HashingVectoriser has no fitted state to discard.
+func (v *HashingVectoriser) Reset() {}
 
-		for i, word := range stopWords {
-			if i != 0 {
-				reStr += `|`
-			}
-			reStr += `\Q` + word + `\E`
-		}
-		reStr += ")\\z"
-		stop = regexp.MustCompile(reStr)
+// Transform hashes the terms within each document into a NumFeatures x len(docs)
+// term document matrix, returned as a *sparse.CSR.  An error is returned if NumFeatures
+// is not between 1 and 2^32 - 1.
+func (v *HashingVectoriser) Transform(docs ...string) (mat64.Matrix, error) {
+	if err := checkNumFeatures(v.NumFeatures); err != nil {
+		return nil, err
+	}
+	return v.assemble(v.transformColumns(docs)), nil
+}
+
+// FitStream does nothing as the HashingVectoriser is stateless and requires no
+// training.  No documents are read from src.
+func (v *HashingVectoriser) FitStream(src DocumentSource) (Vectoriser, error) {
+	return v, nil
+}
+
+// TransformStream hashes the terms within each document read from src into a
+// NumFeatures x n term document matrix (where n is the number of documents), returned
+// as a *sparse.CSR.  Documents are read and processed in batches so only the (sparse)
+// output matrix is retained in memory.
+func (v *HashingVectoriser) TransformStream(src DocumentSource) (mat64.Matrix, error) {
+	if err := checkNumFeatures(v.NumFeatures); err != nil {
+		return nil, err
 	}
-	return &DOKCountVectoriser1{
-		Vocabulary:    make(map[string]int),
-		wordTokeniser: regexp.MustCompile("\\w+"),
-		stopWords:     stop,
+	shards, err := streamColumns(src, v.transformColumns)
+	if err != nil {
+		return nil, err
 	}
+	return v.assemble(shards), nil
 }
 
-func (v *DOKCountVectoriser1) Fit(train ...string) *DOKCountVectoriser1 {
-	i := 0
-	for _, doc := range train {
-		words := v.tokenise(doc)
+// transformColumns hashes the terms within each document into shards of compressed
+// sparse columns.
+func (v *HashingVectoriser) transformColumns(docs []string) []*compressedColumns {
+	checkStopWords := v.checkStopWords()
+
+	// build the matrix in compressed sparse column (CSC) form, one document (column)
+	// at a time, and then convert to CSR.  Each shard of documents is built as a
+	// separate set of columns so shards may be processed concurrently and then joined.
+	shards := make([]*compressedColumns, v.shards(len(docs)))
+
+	v.parallel(len(docs), func(shard, start, end int) {
+		cols := newCompressedColumns(end - start)
+		counts := make(map[int]float64)
 
-		for _, word := range words {
-			_, exists := v.Vocabulary[word]
-			if !exists {
-				// if enabled, remove stop words
-				if v.stopWords != nil {
-					if v.stopWords.MatchString(word) {
-						continue
-					}
+		for d := start; d < end; d++ {
+			words := v.analyse(docs[d], v.isStopWord)
+
+			for _, word := range words {
+				if checkStopWords && v.isStopWord(word) {
+					continue
+				}
+				h := hash(word)
+				i := int(h % uint32(v.NumFeatures))
+
+				if v.AlternateSign && h&(1<<31) != 0 {
+					counts[i]--
+				} else {
+					counts[i]++
 				}
-				v.Vocabulary[word] = i
-				i++
 			}
+
+			for i, count := range counts {
+				if count != 0 {
+					cols.ind = append(cols.ind, i)
+					cols.data = append(cols.data, count)
+				}
+				delete(counts, i)
+			}
+			cols.endColumn()
 		}
-	}
+		shards[shard] = cols
+	})
 
-	return v
+	return shards
 }
 
-func (v *DOKCountVectoriser1) Transform(docs ...string) (*sparse.DOK, error) {
-	mat := sparse.NewDOK(len(v.Vocabulary), len(docs))
+// assemble joins the shards of compressed sparse columns into a single CSR matrix.
+func (v *HashingVectoriser) assemble(shards []*compressedColumns) mat64.Matrix {
+	indptr, ind, data := concatColumns(shards)
 
-	for d, doc := range docs {
-		words := v.tokenise(doc)
+	ia, ja, vals := transposeCompressed(v.NumFeatures, indptr, ind, data)
+
+	return sparse.NewCSR(v.NumFeatures, len(indptr)-1, ia, ja, vals)
+}
 
-		for _, word := range words {
-			i, exists := v.Vocabulary[word]
+// FitTransform is exactly equivalent to calling Transform() as the HashingVectoriser
+// requires no fitting.
+func (v *HashingVectoriser) FitTransform(docs ...string) (mat64.Matrix, error) {
+	return v.Transform(docs...)
+}
 
-			if exists {
-				mat.Set(i, d, mat.At(i, d)+1)
-			}
-		}
-	}
-	return mat, nil
+// isStopWord returns true if word is present in the stop word map.  A nil map (stop
+// word removal disabled) contains no words.
+func (v *HashingVectoriser) isStopWord(word string) bool {
+	return v.stopWords[word]
 }
 
-func (v *DOKCountVectoriser1) FitTransform(docs ...string) (*sparse.DOK, error) {
-	return v.Fit(docs...).Transform(docs...)
+// SetStopWords sets the list of stop words to remove, building a map for lookups.  An
+// empty list disables stop word removal.
+func (v *HashingVectoriser) SetStopWords(words []string) {
+	v.stopWordList = uniqueStopWords(words)
+	v.stopWords = newMapStopWords(v.stopWordList)
 }
 
-func (v *DOKCountVectoriser1) tokenise(text string) []string {
-	// convert content to lower case
-	c := strings.ToLower(text)
+// hash returns the 32 bit FNV-1a hash of s.  It is implemented inline, rather than using
+// hash/fnv, to avoid converting s to a []byte.
+func hash(s string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	h := uint32(offset32)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= prime32
+	}
+	return h
+}
 
-	// match whole words, removing any punctuation/whitespace
-	words := v.wordTokeniser.FindAllString(c, -1)
+// transposeCompressed converts a matrix in compressed sparse format (CSR or CSC) into the
+// alternate compressed format.  indptr, ind and data are the index pointers, indices and
+// values of the source matrix and m is the number of possible index values (the length of
+// the dimension being indexed by ind).  The returned slices represent the same matrix in
+// the alternate format with indices sorted in ascending order.
+func transposeCompressed(m int, indptr, ind []int, data []float64) ([]int, []int, []float64) {
+	tptr := make([]int, m+1)
+	tind := make([]int, len(ind))
+	tdata := make([]float64, len(data))
+
+	// count the non-zeros for each index and calculate the index pointers
+	for _, i := range ind {
+		tptr[i+1]++
+	}
+	for i := 0; i < m; i++ {
+		tptr[i+1] += tptr[i]
+	}
+
+	// scatter the values into place in order
+	next := make([]int, m)
+	copy(next, tptr)
+	for j := 0; j < len(indptr)-1; j++ {
+		for k := indptr[j]; k < indptr[j+1]; k++ {
+			i := ind[k]
+			tind[next[i]] = j
+			tdata[next[i]] = data[k]
+			next[i]++
+		}
+	}
 
-	return words
+	return tptr, tind, tdata
 }
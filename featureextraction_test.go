@@ -5,15 +5,34 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/gonum/matrix/mat64"
 	"github.com/james-bowman/nlp"
+	"github.com/james-bowman/sparse"
 )
 
 func Load(newsgroups ...string) []string {
-	root := "../datasets/20-newsgroups"
 	var files []string
 
+	for _, path := range Paths(newsgroups...) {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Printf("Failed to read file: '%s' caused by %s\n", path, err.Error())
+		}
+		files = append(files, string(b))
+	}
+
+	return files
+}
+
+// Paths returns the paths of all the files within the specified newsgroups (or all
+// newsgroups if none are specified).
+func Paths(newsgroups ...string) []string {
+	root := "../datasets/20-newsgroups"
+	var paths []string
+
 	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if info.IsDir() {
 			if path == root || len(newsgroups) == 0 {
@@ -31,17 +50,60 @@ func Load(newsgroups ...string) []string {
 			return nil
 		}
 
-		// process files
-		b, err := ioutil.ReadFile(path)
-		if err != nil {
-			log.Printf("Failed to read file: '%s' caused by %s\n", path, err.Error())
-		}
-		files = append(files, string(b))
+		paths = append(paths, path)
 
 		return nil
 	})
 
-	return files
+	return paths
+}
+
+func TestVectoriserRefitAndPartialFit(t *testing.T) {
+	train := []string{"The quick brown fox", "jumped over the lazy dog"}
+	more := []string{"The lazy brown cow", "a quick red fox"}
+	all := append(append([]string{}, train...), more...)
+
+	for _, v := range Vectorisers {
+		t.Run(v.Name, func(t *testing.T) {
+			// refitting should discard the previous vocabulary
+			vect := v.New(true)
+			vect.Fit(train...)
+			got, _ := vect.FitTransform(more...)
+			want, _ := v.New(true).FitTransform(more...)
+
+			if !mat64.Equal(got, want) {
+				t.Errorf("Refit: expected\n%v\nbut got\n%v", mat64.Formatted(want), mat64.Formatted(got))
+			}
+
+			// partial fitting should extend the existing vocabulary
+			vect.Fit(train...)
+			vect.PartialFit(more...)
+			got, _ = vect.Transform(all...)
+			want, _ = v.New(true).FitTransform(all...)
+
+			if !mat64.Equal(got, want) {
+				t.Errorf("PartialFit: expected\n%v\nbut got\n%v", mat64.Formatted(want), mat64.Formatted(got))
+			}
+		})
+	}
+}
+
+func TestHashingVectoriserNumFeatures(t *testing.T) {
+	docs := []string{"The quick brown fox", "jumped over the lazy dog"}
+
+	for _, numFeatures := range []int{0, -1} {
+		vect, _ := NewHashingVectoriser(true, 16)
+		vect.NumFeatures = numFeatures
+		if _, err := vect.Transform(docs...); err == nil {
+			t.Errorf("%d: expected error from Transform but got none", numFeatures)
+		}
+		if _, err := vect.TransformStream(NewReaderSource(strings.NewReader(strings.Join(docs, "\n")), nil)); err == nil {
+			t.Errorf("%d: expected error from TransformStream but got none", numFeatures)
+		}
+		if vect, err := NewHashingVectoriser(true, numFeatures); err == nil || vect != nil {
+			t.Errorf("%d: expected constructor to return an error but got %v", numFeatures, err)
+		}
+	}
 }
 
 // Benchmark stop word removal datastructure/algorithms
@@ -94,6 +156,87 @@ func BenchmarkCountVectoriserFitWithTrieStopWordRemoval(b *testing.B) {
 	}
 }
 
+// Minimal perfect hash based stop word lookup and removal
+func BenchmarkCountVectoriserFitWithPerfectHashStopWordRemoval(b *testing.B) {
+	files := Load()
+
+	vect := NewCountVectoriser4(true)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		vect.Fit(files...)
+	}
+}
+
+// Binary search of sorted slice based stop word lookup and removal
+func BenchmarkCountVectoriserFitWithSortedSliceStopWordRemoval(b *testing.B) {
+	files := Load()
+
+	vect := NewCountVectoriser5(true)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		vect.Fit(files...)
+	}
+}
+
+// Length bucketed (switch equivalent) stop word lookup and removal
+func BenchmarkCountVectoriserFitWithLengthBucketedStopWordRemoval(b *testing.B) {
+	files := Load()
+
+	vect := NewCountVectoriser6(true)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		vect.Fit(files...)
+	}
+}
+
+// Bloom filter prefiltered map based stop word lookup and removal
+func BenchmarkCountVectoriserFitWithBloomStopWordRemoval(b *testing.B) {
+	files := Load()
+
+	vect := NewCountVectoriser7(true)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		vect.Fit(files...)
+	}
+}
+
+// Benchmark every registered Vectoriser implementation
+
+func BenchmarkVectoriserFit(b *testing.B) {
+	files := Load("sci.space", "sci.electronics")
+
+	for _, v := range Vectorisers {
+		b.Run(v.Name, func(b *testing.B) {
+			vect := v.New(false)
+
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				vect.Fit(files...)
+			}
+		})
+	}
+}
+
+func BenchmarkVectoriserTransform(b *testing.B) {
+	files := Load("sci.space", "sci.electronics")
+
+	for _, v := range Vectorisers {
+		b.Run(v.Name, func(b *testing.B) {
+			vect := v.New(false)
+			vect.Fit(files...)
+
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				vect.Transform(files...)
+			}
+		})
+	}
+}
+
 // Benchmark feature extraction vectorisation into Dense vs Sparse matrices
 
 // Baseline Dense matrix vectorisation
@@ -122,6 +265,86 @@ func BenchmarkDOKCountVectoriserTransform(b *testing.B) {
 	}
 }
 
+// Benchmark direct assembly of sparse matrix arrays against DOK + conversion
+func BenchmarkDOKCountVectoriserTransformToCSR(b *testing.B) {
+	files := Load("sci.space", "sci.electronics")
+
+	vect := NewDOKCountVectoriser1(false)
+	vect.Fit(files...)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		mat, _ := vect.Transform(files...)
+		mat.(*sparse.DOK).ToCSR()
+	}
+}
+
+func benchmarkSparseCountVectoriserTransform(format SparseFormat, b *testing.B) {
+	files := Load("sci.space", "sci.electronics")
+
+	vect := NewSparseCountVectoriser(false)
+	vect.Format = format
+	vect.Fit(files...)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		vect.Transform(files...)
+	}
+}
+
+func BenchmarkSparseCountVectoriserTransformToCSR(b *testing.B) {
+	benchmarkSparseCountVectoriserTransform(CSRFormat, b)
+}
+
+func BenchmarkSparseCountVectoriserTransformToCSC(b *testing.B) {
+	benchmarkSparseCountVectoriserTransform(CSCFormat, b)
+}
+
+func BenchmarkSparseCountVectoriserTransformToCOO(b *testing.B) {
+	benchmarkSparseCountVectoriserTransform(COOFormat, b)
+}
+
+// Benchmark HashingVectoriser vectorisation into CSR against DOK + conversion to CSR
+func BenchmarkDOKCountVectoriserFitTransformToCSR(b *testing.B) {
+	files := Load("sci.space", "sci.electronics")
+
+	vect := NewDOKCountVectoriser1(false)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		mat, _ := vect.FitTransform(files...)
+		mat.(*sparse.DOK).ToCSR()
+	}
+}
+
+func BenchmarkHashingVectoriserFitTransform(b *testing.B) {
+	files := Load("sci.space", "sci.electronics")
+
+	vect, _ := NewHashingVectoriser(false, DefaultNumFeatures)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		vect.FitTransform(files...)
+	}
+}
+
+func BenchmarkHashingVectoriserFitTransformAlternateSign(b *testing.B) {
+	files := Load("sci.space", "sci.electronics")
+
+	vect, _ := NewHashingVectoriser(false, DefaultNumFeatures)
+	vect.AlternateSign = true
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		vect.FitTransform(files...)
+	}
+}
+
 func BenchmarkDenseTfidfFitTransform(b *testing.B) {
 	files := Load("sci.space", "sci.electronics")
 
@@ -148,7 +371,7 @@ func BenchmarkSparseTfidfFitTransform(b *testing.B) {
 
 	b.ResetTimer()
 	for n := 0; n < b.N; n++ {
-		trans.FitTransform(mat.ToCSR())
+		trans.FitTransform(mat.(*sparse.DOK).ToCSR())
 	}
 }
 
@@ -189,7 +412,7 @@ func BenchmarkDenseApplyTfidfFitWithCSR(b *testing.B) {
 	vect.Fit(files...)
 	mat, _ := vect.Transform(files...)
 
-	csr := mat.ToCSR()
+	csr := mat.(*sparse.DOK).ToCSR()
 
 	trans := &TfidfTransformer3{}
 
@@ -205,7 +428,7 @@ func BenchmarkCSRTfidfFitWithCSR(b *testing.B) {
 	vect := NewDOKCountVectoriser1(false)
 	vect.Fit(files...)
 	mat, _ := vect.Transform(files...)
-	csr := mat.ToCSR()
+	csr := mat.(*sparse.DOK).ToCSR()
 
 	trans := &SparseTfidfTransformer{}
 
@@ -253,7 +476,7 @@ func BenchmarkDenseApplyTfidfTransformWithCSR(b *testing.B) {
 	vect := NewDOKCountVectoriser1(false)
 	vect.Fit(files...)
 	mat, _ := vect.Transform(files...)
-	csr := mat.ToCSR()
+	csr := mat.(*sparse.DOK).ToCSR()
 
 	trans := &TfidfTransformer3{}
 	trans.Fit(csr)
@@ -275,7 +498,7 @@ func BenchmarkDenseApplyTfidfTransformWithConvFromDOKToCSR(b *testing.B) {
 	trans.Fit(mat)
 
 	b.ResetTimer()
-	csr := mat.ToCSR()
+	csr := mat.(*sparse.DOK).ToCSR()
 	for n := 0; n < b.N; n++ {
 		trans.Transform(csr)
 	}
@@ -304,7 +527,7 @@ func BenchmarkDenseMulTfidfTransformWithCSR(b *testing.B) {
 	vect := NewDOKCountVectoriser1(false)
 	vect.Fit(files...)
 	mat, _ := vect.Transform(files...)
-	csr := mat.ToCSR()
+	csr := mat.(*sparse.DOK).ToCSR()
 
 	trans := &TfidfTransformer1{}
 	trans.Fit(csr)
@@ -337,7 +560,7 @@ func BenchmarkCSRTfidfTransformWithCSR(b *testing.B) {
 	vect := NewDOKCountVectoriser1(false)
 	vect.Fit(files...)
 	mat, _ := vect.Transform(files...)
-	csr := mat.ToCSR()
+	csr := mat.(*sparse.DOK).ToCSR()
 
 	trans := &SparseTfidfTransformer{}
 	trans.Fit(csr)
@@ -384,7 +607,7 @@ func BenchmarkCSRSVD(b *testing.B) {
 	vect := NewDOKCountVectoriser1(false)
 	vect.Fit(files...)
 	mat, _ := vect.Transform(files...)
-	csr := mat.ToCSR()
+	csr := mat.(*sparse.DOK).ToCSR()
 
 	trans := nlp.NewTruncatedSVD(100)
 
@@ -418,7 +641,7 @@ func BenchmarkSparseEndToEndVectAndTrans(b *testing.B) {
 
 	for n := 0; n < b.N; n++ {
 		mat, _ := vect.FitTransform(files...)
-		trans.FitTransform(mat.ToCSR())
+		trans.FitTransform(mat.(*sparse.DOK).ToCSR())
 	}
 }
 
@@ -449,7 +672,23 @@ func BenchmarkSparseEndToEndFull(b *testing.B) {
 
 	for n := 0; n < b.N; n++ {
 		mat, _ := vect.FitTransform(files...)
-		csr := mat.ToCSR()
+		csr := mat.(*sparse.DOK).ToCSR()
+		tfidf, _ := trans.FitTransform(csr)
+		red.FitTransform(tfidf)
+	}
+}
+
+func BenchmarkSparseEndToEndFullDirectCSR(b *testing.B) {
+	files := Load("sci.space", "sci.electronics")
+
+	b.ResetTimer()
+
+	vect := NewSparseCountVectoriser(false)
+	trans := &SparseTfidfTransformer{}
+	red := nlp.NewTruncatedSVD(100)
+
+	for n := 0; n < b.N; n++ {
+		csr, _ := vect.FitTransform(files...)
 		tfidf, _ := trans.FitTransform(csr)
 		red.FitTransform(tfidf)
 	}
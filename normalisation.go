@@ -0,0 +1,70 @@
+package nlpbench
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalisationForm specifies the Unicode normalisation form applied to documents
+// before tokenisation.  Without normalisation, the same text may be encoded as
+// different sequences of runes, for example "café" may be written with a precomposed
+// "é" or as "e" followed by a combining acute accent, producing separate terms.
+type NormalisationForm int
+
+const (
+	// NoNormalisation leaves documents unchanged.
+	NoNormalisation NormalisationForm = iota
+
+	// NFC applies canonical decomposition followed by canonical composition so that
+	// canonically equivalent sequences, such as precomposed and decomposed accented
+	// letters, are encoded identically.
+	NFC
+
+	// NFKC applies compatibility decomposition followed by canonical composition.  In
+	// addition to the equivalences of NFC, compatibility characters such as ligatures
+	// ("ﬁ"), full width letters and superscripts are replaced by their plain
+	// equivalents.
+	NFKC
+)
+
+// normalise applies the Unicode normalisation, accent stripping and case folding
+// options of the Analyser to text.  If no options are enabled, text is returned
+// unchanged without allocating.
+func (a *Analyser) normalise(text string) string {
+	if a.StripAccents {
+		// decompose so that accents are separated from the letters they modify as
+		// non-spacing marks, remove the marks and then recompose whatever remains
+		decompose := norm.NFD
+		if a.Normalisation == NFKC {
+			decompose = norm.NFKD
+		}
+		text = norm.NFC.String(strings.Map(removeMark, decompose.String(text)))
+	} else {
+		switch a.Normalisation {
+		case NFC:
+			text = norm.NFC.String(text)
+		case NFKC:
+			text = norm.NFKC.String(text)
+		}
+	}
+
+	if a.CaseFold {
+		// Casers are stateful and so a new one is used for each document to allow
+		// documents to be analysed concurrently
+		text = cases.Fold().String(text)
+	}
+
+	return text
+}
+
+// removeMark returns -1, so that strings.Map drops the rune, if r is a non-spacing mark
+// (such as a combining accent) and otherwise returns r unchanged.
+func removeMark(r rune) rune {
+	if unicode.Is(unicode.Mn, r) {
+		return -1
+	}
+	return r
+}
@@ -0,0 +1,102 @@
+package nlpbench
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalisation(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(a *Analyser)
+		docs      []string
+		want      []string
+	}{
+		{
+			name:      "None",
+			configure: func(a *Analyser) {},
+			docs:      []string{"café", "cafe\u0301"},
+			want:      []string{"café", "cafe\u0301"},
+		},
+		{
+			name:      "NFC",
+			configure: func(a *Analyser) { a.Normalisation = NFC },
+			docs:      []string{"café", "cafe\u0301", "ﬁne"},
+			want:      []string{"café", "ﬁne"},
+		},
+		{
+			name:      "NFKC",
+			configure: func(a *Analyser) { a.Normalisation = NFKC },
+			docs:      []string{"café", "cafe\u0301", "ﬁne", "ｆｉｎｅ"},
+			want:      []string{"café", "fine"},
+		},
+		{
+			name:      "StripAccents",
+			configure: func(a *Analyser) { a.StripAccents = true },
+			docs:      []string{"café", "cafe\u0301", "cafe", "Ünïcödé"},
+			want:      []string{"cafe", "unicode"},
+		},
+		{
+			name:      "CaseFold",
+			configure: func(a *Analyser) { a.CaseFold = true },
+			docs:      []string{"straße", "STRASSE", "Strasse"},
+			want:      []string{"strasse"},
+		},
+		{
+			name: "All",
+			configure: func(a *Analyser) {
+				a.Normalisation = NFKC
+				a.StripAccents = true
+				a.CaseFold = true
+			},
+			docs: []string{"Straße café", "STRASSE CAFÉ", "ﬁne cafe"},
+			want: []string{"strasse", "cafe", "fine"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vect := NewCountVectoriser2(false)
+			vect.Tokeniser = &UnicodeTokeniser{}
+			test.configure(&vect.Analyser)
+			vect.Fit(test.docs...)
+
+			got := make([]string, len(vect.Vocabulary))
+			for term, i := range vect.Vocabulary {
+				got[i] = term
+			}
+			if strings.Join(got, "|") != strings.Join(test.want, "|") {
+				t.Errorf("Expected vocabulary %q but got %q", test.want, got)
+			}
+		})
+	}
+}
+
+// Benchmark the cost of the Unicode normalisation options during Fit
+func BenchmarkNormalisationFit(b *testing.B) {
+	files := Load("sci.space", "sci.electronics")
+
+	options := []struct {
+		name      string
+		configure func(a *Analyser)
+	}{
+		{"None", func(a *Analyser) {}},
+		{"NFC", func(a *Analyser) { a.Normalisation = NFC }},
+		{"NFKC", func(a *Analyser) { a.Normalisation = NFKC }},
+		{"StripAccents", func(a *Analyser) { a.StripAccents = true }},
+		{"CaseFold", func(a *Analyser) { a.CaseFold = true }},
+	}
+
+	for _, o := range options {
+		b.Run(o.name, func(b *testing.B) {
+			vect := NewCountVectoriser2(true)
+			vect.Tokeniser = &UnicodeTokeniser{}
+			o.configure(&vect.Analyser)
+
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				vect.Fit(files...)
+			}
+		})
+	}
+}
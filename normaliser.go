@@ -0,0 +1,199 @@
+package nlpbench
+
+import (
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/james-bowman/sparse"
+)
+
+// Norm specifies the vector norm used by the Normaliser and SparseNormaliser to
+// normalise the columns (documents) of a term document matrix.
+type Norm int
+
+const (
+	// L2Norm scales each document to unit Euclidean length so that the dot product of
+	// two documents is their cosine similarity.  This is the zero value.
+	L2Norm Norm = iota
+
+	// L1Norm scales each document so that the absolute values of its elements sum to 1.
+	L1Norm
+
+	// MaxNorm scales each document so that its largest absolute value is 1.
+	MaxNorm
+)
+
+// accumulate returns the running norm acc updated with the value v.  For L2Norm the
+// running value is the sum of squares and must be finished with finish.
+func (norm Norm) accumulate(acc float64, v float64) float64 {
+	switch norm {
+	case L1Norm:
+		return acc + math.Abs(v)
+	case MaxNorm:
+		return math.Max(acc, math.Abs(v))
+	}
+	return acc + v*v
+}
+
+// finish returns the norm from the running value accumulated with accumulate.
+func (norm Norm) finish(acc float64) float64 {
+	if norm == L2Norm {
+		return math.Sqrt(acc)
+	}
+	return acc
+}
+
+// columnNorms returns the norm of each column of mat.  For CSR and CSC matrices, the
+// norms are calculated directly from the non zero values in the underlying storage.
+func (norm Norm) columnNorms(mat mat64.Matrix) []float64 {
+	m, n := mat.Dims()
+	norms := make([]float64, n)
+
+	switch mat := mat.(type) {
+	case *sparse.CSR:
+		raw := mat.RawMatrix()
+		for k, j := range raw.Ind {
+			norms[j] = norm.accumulate(norms[j], raw.Data[k])
+		}
+	case *sparse.CSC:
+		raw := mat.RawMatrix()
+		for j := range norms {
+			for _, v := range raw.Data[raw.Indptr[j]:raw.Indptr[j+1]] {
+				norms[j] = norm.accumulate(norms[j], v)
+			}
+		}
+	default:
+		for i := 0; i < m; i++ {
+			for j := range norms {
+				norms[j] = norm.accumulate(norms[j], mat.At(i, j))
+			}
+		}
+	}
+
+	for j, acc := range norms {
+		norms[j] = norm.finish(acc)
+	}
+
+	return norms
+}
+
+// divideByNorm returns v divided by the norm of its column.  Columns with a zero norm
+// (empty documents) are left unchanged.
+func divideByNorm(v float64, norm float64) float64 {
+	if norm == 0 {
+		return v
+	}
+	return v / norm
+}
+
+// Normaliser normalises the columns (documents) of a term document matrix, typically
+// after tf-idf weighting, so that documents of different lengths are comparable.
+// Normaliser is stateless, as each document is normalised independently, so Fit is a
+// no-op and is provided to satisfy the Transformer interface.  The normalised matrix
+// is always dense although CSR and CSC input matrices are normalised directly from
+// their non zero values.  SparseNormaliser should be used to retain sparse formats.
+type Normaliser struct {
+	Norm Norm
+}
+
+// NewNormaliser constructs a new Normaliser using the specified norm.
+func NewNormaliser(norm Norm) *Normaliser {
+	return &Normaliser{Norm: norm}
+}
+
+// Fit does nothing as there is nothing to fit.
+func (t *Normaliser) Fit(mat mat64.Matrix) Transformer {
+	return t
+}
+
+// Transform returns a new dense matrix containing the normalised columns of mat.
+func (t *Normaliser) Transform(mat mat64.Matrix) (*mat64.Dense, error) {
+	m, n := mat.Dims()
+	norms := t.Norm.columnNorms(mat)
+
+	switch mat := mat.(type) {
+	case *sparse.CSR:
+		product := mat64.NewDense(m, n, nil)
+		raw := mat.RawMatrix()
+		for i := 0; i < m; i++ {
+			for k := raw.Indptr[i]; k < raw.Indptr[i+1]; k++ {
+				j := raw.Ind[k]
+				product.Set(i, j, divideByNorm(raw.Data[k], norms[j]))
+			}
+		}
+		return product, nil
+	case *sparse.CSC:
+		product := mat64.NewDense(m, n, nil)
+		raw := mat.RawMatrix()
+		for j := 0; j < n; j++ {
+			for k := raw.Indptr[j]; k < raw.Indptr[j+1]; k++ {
+				product.Set(raw.Ind[k], j, divideByNorm(raw.Data[k], norms[j]))
+			}
+		}
+		return product, nil
+	}
+
+	product := mat64.DenseCopyOf(mat)
+	for i := 0; i < m; i++ {
+		for j := 0; j < n; j++ {
+			product.Set(i, j, divideByNorm(product.At(i, j), norms[j]))
+		}
+	}
+	return product, nil
+}
+
+// FitTransform is equivalent to calling Fit followed by Transform.
+func (t *Normaliser) FitTransform(mat mat64.Matrix) (*mat64.Dense, error) {
+	return t.Fit(mat).Transform(mat)
+}
+
+// SparseNormaliser normalises the columns (documents) of a term document matrix, like
+// Normaliser, but retains the format of CSR and CSC input matrices which are normalised
+// directly within copies of their underlying storage.  All other matrices are returned
+// as dense matrices.
+type SparseNormaliser struct {
+	Norm Norm
+}
+
+// NewSparseNormaliser constructs a new SparseNormaliser using the specified norm.
+func NewSparseNormaliser(norm Norm) *SparseNormaliser {
+	return &SparseNormaliser{Norm: norm}
+}
+
+// Fit does nothing as there is nothing to fit.
+func (t *SparseNormaliser) Fit(mat mat64.Matrix) SparseTransformer {
+	return t
+}
+
+// Transform returns a new matrix containing the normalised columns of mat.
+func (t *SparseNormaliser) Transform(mat mat64.Matrix) (mat64.Matrix, error) {
+	m, n := mat.Dims()
+
+	switch mat := mat.(type) {
+	case *sparse.CSR:
+		norms := t.Norm.columnNorms(mat)
+		raw := mat.RawMatrix()
+		data := make([]float64, len(raw.Data))
+		for k, j := range raw.Ind {
+			data[k] = divideByNorm(raw.Data[k], norms[j])
+		}
+		return sparse.NewCSR(m, n, append([]int(nil), raw.Indptr...), append([]int(nil), raw.Ind...), data), nil
+	case *sparse.CSC:
+		norms := t.Norm.columnNorms(mat)
+		raw := mat.RawMatrix()
+		data := make([]float64, len(raw.Data))
+		for j := 0; j < n; j++ {
+			for k := raw.Indptr[j]; k < raw.Indptr[j+1]; k++ {
+				data[k] = divideByNorm(raw.Data[k], norms[j])
+			}
+		}
+		return sparse.NewCSC(m, n, append([]int(nil), raw.Indptr...), append([]int(nil), raw.Ind...), data), nil
+	}
+
+	return (&Normaliser{Norm: t.Norm}).Transform(mat)
+}
+
+// FitTransform is equivalent to calling Fit followed by Transform.
+func (t *SparseNormaliser) FitTransform(mat mat64.Matrix) (mat64.Matrix, error) {
+	return t.Fit(mat).Transform(mat)
+}
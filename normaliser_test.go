@@ -0,0 +1,111 @@
+package nlpbench
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/james-bowman/sparse"
+)
+
+func TestNormalisers(t *testing.T) {
+	weights := mat64.NewDense(3, 3, []float64{
+		3, 0, -1,
+		4, 0, 0,
+		0, 0, 2,
+	})
+
+	r5 := math.Sqrt(5)
+	tests := []struct {
+		name string
+		norm Norm
+		want *mat64.Dense
+	}{
+		{"L2", L2Norm, mat64.NewDense(3, 3, []float64{
+			0.6, 0, -1 / r5,
+			0.8, 0, 0,
+			0, 0, 2 / r5,
+		})},
+		{"L1", L1Norm, mat64.NewDense(3, 3, []float64{
+			3.0 / 7, 0, -1.0 / 3,
+			4.0 / 7, 0, 0,
+			0, 0, 2.0 / 3,
+		})},
+		{"Max", MaxNorm, mat64.NewDense(3, 3, []float64{
+			0.75, 0, -0.5,
+			1, 0, 0,
+			0, 0, 1,
+		})},
+	}
+
+	dok := sparse.NewDOK(3, 3)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if v := weights.At(i, j); v != 0 {
+				dok.Set(i, j, v)
+			}
+		}
+	}
+	inputs := []mat64.Matrix{weights, dok, dok.ToCSR(), dok.ToCSC()}
+
+	for _, test := range tests {
+		for _, input := range inputs {
+			t.Run(fmt.Sprintf("Dense/%s/%T", test.name, input), func(t *testing.T) {
+				got, _ := NewNormaliser(test.norm).FitTransform(input)
+				if !mat64.EqualApprox(got, test.want, 1e-12) {
+					t.Errorf("Expected\n%v\nbut got\n%v", mat64.Formatted(test.want), mat64.Formatted(got))
+				}
+			})
+
+			t.Run(fmt.Sprintf("Sparse/%s/%T", test.name, input), func(t *testing.T) {
+				got, _ := NewSparseNormaliser(test.norm).FitTransform(input)
+
+				wantType := reflect.TypeOf(&mat64.Dense{})
+				switch input.(type) {
+				case *sparse.CSR, *sparse.CSC:
+					wantType = reflect.TypeOf(input)
+				}
+				if reflect.TypeOf(got) != wantType {
+					t.Errorf("Expected matrix of type %v but got %T", wantType, got)
+				}
+				if !mat64.EqualApprox(got, test.want, 1e-12) {
+					t.Errorf("Expected\n%v\nbut got\n%v", mat64.Formatted(test.want), mat64.Formatted(got))
+				}
+				if sharesStorage(got, input) {
+					t.Errorf("Expected normalised matrix not to share storage with the input")
+				}
+			})
+		}
+	}
+}
+
+// Benchmark L2 normalisation of tf-idf weighted dense and sparse matrices
+
+func BenchmarkNormaliserTransform(b *testing.B) {
+	files := Load("sci.space", "sci.electronics")
+	vect := NewSparseCountVectoriser(true)
+	counts, _ := vect.FitTransform(files...)
+	csr, _ := (&SparseTfidfTransformer{}).FitTransform(counts)
+	dense := csr.(*sparse.CSR).ToDense()
+
+	b.Run("Dense", func(b *testing.B) {
+		t := NewNormaliser(L2Norm)
+		for n := 0; n < b.N; n++ {
+			t.Transform(dense)
+		}
+	})
+	b.Run("CSRToDense", func(b *testing.B) {
+		t := NewNormaliser(L2Norm)
+		for n := 0; n < b.N; n++ {
+			t.Transform(csr)
+		}
+	})
+	b.Run("CSR", func(b *testing.B) {
+		t := NewSparseNormaliser(L2Norm)
+		for n := 0; n < b.N; n++ {
+			t.Transform(csr)
+		}
+	})
+}
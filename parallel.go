@@ -0,0 +1,103 @@
+package nlpbench
+
+import (
+	"sync"
+)
+
+// shardsPerWorker is the number of shards the documents are split into for each worker
+// goroutine when processing concurrently.  Using more shards than workers helps balance
+// the load across workers where documents vary in length.
+const shardsPerWorker = 4
+
+// shards returns the number of contiguous shards to split n documents into for
+// processing.  If Concurrency is less than 2, all documents are processed as a single
+// shard.
+func (a *Analyser) shards(n int) int {
+	if a.Concurrency < 2 || n < 2 {
+		return 1
+	}
+	s := a.Concurrency * shardsPerWorker
+	if s > n {
+		s = n
+	}
+	return s
+}
+
+// parallel splits n documents into contiguous shards and calls fn for each shard,
+// passing the shard number and the range of document indices [start, end) in the shard.
+// Shards are processed concurrently by a pool of Concurrency worker goroutines and
+// parallel returns once all shards have been processed.  If Concurrency is less than 2,
+// fn is called once for all documents on the calling goroutine.
+func (a *Analyser) parallel(n int, fn func(shard, start, end int)) {
+	shards := a.shards(n)
+	if shards == 1 {
+		fn(0, 0, n)
+		return
+	}
+
+	work := make(chan int, shards)
+	for s := 0; s < shards; s++ {
+		work <- s
+	}
+	close(work)
+
+	var wg sync.WaitGroup
+	for w := 0; w < a.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s := range work {
+				fn(s, s*n/shards, (s+1)*n/shards)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// compressedColumns holds a sparse matrix in compressed sparse column (CSC) form, built
+// up one column (document) at a time.
+type compressedColumns struct {
+	indptr []int
+	ind    []int
+	data   []float64
+}
+
+// newCompressedColumns creates a new compressedColumns with room for the specified
+// number of columns.
+func newCompressedColumns(cols int) *compressedColumns {
+	return &compressedColumns{indptr: make([]int, 1, cols+1)}
+}
+
+// endColumn marks the end of the current column.
+func (c *compressedColumns) endColumn() {
+	c.indptr = append(c.indptr, len(c.ind))
+}
+
+// concatColumns joins the columns of each of the shards, in order, returning the index
+// pointers, row indices and values of the combined matrix.
+func concatColumns(shards []*compressedColumns) ([]int, []int, []float64) {
+	if len(shards) == 1 {
+		return shards[0].indptr, shards[0].ind, shards[0].data
+	}
+
+	var cols, nnz int
+	for _, s := range shards {
+		cols += len(s.indptr) - 1
+		nnz += len(s.ind)
+	}
+
+	indptr := make([]int, 1, cols+1)
+	ind := make([]int, 0, nnz)
+	data := make([]float64, 0, nnz)
+
+	for _, s := range shards {
+		offset := len(ind)
+		for _, p := range s.indptr[1:] {
+			indptr = append(indptr, p+offset)
+		}
+		ind = append(ind, s.ind...)
+		data = append(data, s.data...)
+	}
+
+	return indptr, ind, data
+}
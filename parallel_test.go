@@ -0,0 +1,102 @@
+package nlpbench
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// analyserOf returns the Analyser embedded within the Vectoriser v.
+func analyserOf(v Vectoriser) *Analyser {
+	switch v := v.(type) {
+	case *CountVectoriser1:
+		return &v.Analyser
+	case *CountVectoriser2:
+		return &v.Analyser
+	case *CountVectoriser3:
+		return &v.Analyser
+	case *CountVectoriser4:
+		return &v.Analyser
+	case *CountVectoriser5:
+		return &v.Analyser
+	case *CountVectoriser6:
+		return &v.Analyser
+	case *CountVectoriser7:
+		return &v.Analyser
+	case *DOKCountVectoriser1:
+		return &v.Analyser
+	case *SparseCountVectoriser:
+		return &v.Analyser
+	case *HashingVectoriser:
+		return &v.Analyser
+	}
+	panic(fmt.Sprintf("unknown vectoriser type %T", v))
+}
+
+func TestVectoriserConcurrency(t *testing.T) {
+	words := strings.Fields("the quick brown fox jumped over the lazy dog and then ran away from a big red space shuttle")
+	docs := make([]string, 50)
+	for d := range docs {
+		docs[d] = strings.Join(words[d%7:len(words)-d%5], " ")
+	}
+
+	for _, v := range Vectorisers {
+		want, _ := v.New(true).FitTransform(docs...)
+
+		for _, concurrency := range []int{2, 3, 8, 100} {
+			t.Run(fmt.Sprintf("%s/%d", v.Name, concurrency), func(t *testing.T) {
+				vect := v.New(true)
+				analyserOf(vect).Concurrency = concurrency
+
+				got, _ := vect.FitTransform(docs...)
+
+				if !mat64.Equal(got, want) {
+					t.Errorf("Expected\n%v\nbut got\n%v", mat64.Formatted(want), mat64.Formatted(got))
+				}
+			})
+		}
+	}
+}
+
+// Benchmark speed up of concurrent Fit and Transform for increasing concurrency up to
+// GOMAXPROCS
+
+func BenchmarkParallelFit(b *testing.B) {
+	files := Load("sci.space", "sci.electronics")
+
+	for _, v := range Vectorisers {
+		for c := 1; c <= runtime.GOMAXPROCS(0); c *= 2 {
+			b.Run(fmt.Sprintf("%s/%d", v.Name, c), func(b *testing.B) {
+				vect := v.New(false)
+				analyserOf(vect).Concurrency = c
+
+				b.ResetTimer()
+				for n := 0; n < b.N; n++ {
+					vect.Fit(files...)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkParallelTransform(b *testing.B) {
+	files := Load("sci.space", "sci.electronics")
+
+	for _, v := range Vectorisers {
+		for c := 1; c <= runtime.GOMAXPROCS(0); c *= 2 {
+			b.Run(fmt.Sprintf("%s/%d", v.Name, c), func(b *testing.B) {
+				vect := v.New(false)
+				analyserOf(vect).Concurrency = c
+				vect.Fit(files...)
+
+				b.ResetTimer()
+				for n := 0; n < b.N; n++ {
+					vect.Transform(files...)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,693 @@
+package nlpbench
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/james-bowman/sparse"
+)
+
+// modelVersion is the version of the serialised model format written by the
+// MarshalBinary and MarshalJSON methods of the vectorisers and transformers.  Decoding
+// a model of any other version will fail.
+const modelVersion = 1
+
+// vectoriserModel is the serialised form of a vectoriser, including its fitted
+// vocabulary and configuration.  Fields not applicable to a particular type of
+// vectoriser are left as their zero values.
+type vectoriserModel struct {
+	Version int `json:"version"`
+
+	Mode        AnalyserMode `json:"mode"`
+	Tokeniser   string       `json:"tokeniser"`
+	Stemmer     string       `json:"stemmer,omitempty"`
+	MinNGram    int          `json:"minNGram"`
+	MaxNGram    int          `json:"maxNGram"`
+	Concurrency int          `json:"concurrency"`
+
+	Normalisation NormalisationForm `json:"normalisation,omitempty"`
+	StripAccents  bool              `json:"stripAccents,omitempty"`
+	CaseFold      bool              `json:"caseFold,omitempty"`
+
+	Vocabulary     map[string]int     `json:"vocabulary,omitempty"`
+	Limits         VocabularyLimits   `json:"limits"`
+	StopWordLookup stopWordLookupType `json:"stopWordLookup,omitempty"`
+	StopWords      []string           `json:"stopWords,omitempty"`
+
+	Format        SparseFormat `json:"format,omitempty"`
+	NumFeatures   int          `json:"numFeatures,omitempty"`
+	AlternateSign bool         `json:"alternateSign,omitempty"`
+}
+
+// transformerModel is the serialised form of a transformer, holding the fitted term
+// weights and configuration.  Fields not applicable to a particular type of transformer
+// are left as their zero values.
+type transformerModel struct {
+	Version int       `json:"version"`
+	Weights []float64 `json:"weights"`
+	IdfMode IdfMode   `json:"idfMode,omitempty"`
+	TfMode  TfMode    `json:"tfMode,omitempty"`
+	Norm    Norm      `json:"norm,omitempty"`
+
+	K1        float64 `json:"k1,omitempty"`
+	B         float64 `json:"b,omitempty"`
+	AvgDocLen float64 `json:"avgDocLen,omitempty"`
+}
+
+// vectoriserModelSource is implemented by vectorisers able to convert themselves to
+// their serialised model form.
+type vectoriserModelSource interface {
+	model() (*vectoriserModel, error)
+}
+
+// vectoriserModeller is implemented by vectorisers able to convert themselves to and
+// from their serialised model form.
+type vectoriserModeller interface {
+	vectoriserModelSource
+	setModel(m *vectoriserModel) error
+}
+
+// transformerModeller is implemented by transformers able to convert themselves to and
+// from their serialised model form.
+type transformerModeller interface {
+	model() *transformerModel
+	setModel(m *transformerModel) error
+}
+
+// gobEncode encodes v using gob.
+func gobEncode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gobDecode decodes gob encoded data into v.
+func gobDecode(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// checkVersion returns an error if version is not a supported model version.
+func checkVersion(version int) error {
+	if version != modelVersion {
+		return fmt.Errorf("nlpbench: unsupported model version %d (expected %d)", version, modelVersion)
+	}
+	return nil
+}
+
+// marshalVectoriser encodes the model of vectoriser v using encode.
+func marshalVectoriser(v vectoriserModelSource, encode func(interface{}) ([]byte, error)) ([]byte, error) {
+	m, err := v.model()
+	if err != nil {
+		return nil, err
+	}
+	m.Version = modelVersion
+	return encode(m)
+}
+
+// unmarshalVectoriser decodes a model from data using decode and restores it into
+// vectoriser v.
+func unmarshalVectoriser(v vectoriserModeller, data []byte, decode func([]byte, interface{}) error) error {
+	var m vectoriserModel
+	if err := decode(data, &m); err != nil {
+		return err
+	}
+	if err := checkVersion(m.Version); err != nil {
+		return err
+	}
+	return v.setModel(&m)
+}
+
+// marshalTransformer encodes the model of transformer t using encode.
+func marshalTransformer(t transformerModeller, encode func(interface{}) ([]byte, error)) ([]byte, error) {
+	m := t.model()
+	m.Version = modelVersion
+	return encode(m)
+}
+
+// unmarshalTransformer decodes a model from data using decode and restores it into
+// transformer t.
+func unmarshalTransformer(t transformerModeller, data []byte, decode func([]byte, interface{}) error) error {
+	var m transformerModel
+	if err := decode(data, &m); err != nil {
+		return err
+	}
+	if err := checkVersion(m.Version); err != nil {
+		return err
+	}
+	return t.setModel(&m)
+}
+
+// tokeniserName returns the name used to identify the Tokeniser t within serialised
+// models.  Only the Tokeniser implementations within this package may be serialised.
+func tokeniserName(t Tokeniser) (string, error) {
+	switch t.(type) {
+	case *RegExpTokeniser:
+		return "regexp", nil
+	case *ScannerTokeniser:
+		return "scanner", nil
+	case *ByteTokeniser:
+		return "byte", nil
+	case *UnicodeTokeniser:
+		return "unicode", nil
+	case *WhitespaceTokeniser:
+		return "whitespace", nil
+	}
+	return "", fmt.Errorf("nlpbench: unable to serialise tokeniser of type %T", t)
+}
+
+// newTokeniser creates a new Tokeniser of the type identified by name (as returned by
+// tokeniserName).
+func newTokeniser(name string) (Tokeniser, error) {
+	switch name {
+	case "regexp":
+		return NewRegExpTokeniser(), nil
+	case "scanner":
+		return &ScannerTokeniser{}, nil
+	case "byte":
+		return &ByteTokeniser{}, nil
+	case "unicode":
+		return &UnicodeTokeniser{}, nil
+	case "whitespace":
+		return &WhitespaceTokeniser{}, nil
+	}
+	return nil, fmt.Errorf("nlpbench: unknown tokeniser %q", name)
+}
+
+// stemmerName returns the name used to identify the Stemmer s within serialised models
+// or an empty string if s is nil.  Only the Stemmer implementations within this package
+// may be serialised.
+func stemmerName(s Stemmer) (string, error) {
+	switch s.(type) {
+	case nil:
+		return "", nil
+	case *Porter2Stemmer:
+		return "porter2", nil
+	}
+	return "", fmt.Errorf("nlpbench: unable to serialise stemmer of type %T", s)
+}
+
+// newStemmer creates a new Stemmer of the type identified by name (as returned by
+// stemmerName).
+func newStemmer(name string) (Stemmer, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case "porter2":
+		return &Porter2Stemmer{}, nil
+	}
+	return nil, fmt.Errorf("nlpbench: unknown stemmer %q", name)
+}
+
+// toModel records the configuration of the Analyser in m.
+func (a *Analyser) toModel(m *vectoriserModel) error {
+	name, err := tokeniserName(a.Tokeniser)
+	if err != nil {
+		return err
+	}
+	stemmer, err := stemmerName(a.Stemmer)
+	if err != nil {
+		return err
+	}
+
+	m.Mode = a.Mode
+	m.Tokeniser = name
+	m.Stemmer = stemmer
+	m.Normalisation = a.Normalisation
+	m.StripAccents = a.StripAccents
+	m.CaseFold = a.CaseFold
+	m.MinNGram = a.MinNGram
+	m.MaxNGram = a.MaxNGram
+	m.Concurrency = a.Concurrency
+
+	return nil
+}
+
+// fromModel restores the configuration of the Analyser from m.
+func (a *Analyser) fromModel(m *vectoriserModel) error {
+	tokeniser, err := newTokeniser(m.Tokeniser)
+	if err != nil {
+		return err
+	}
+	stemmer, err := newStemmer(m.Stemmer)
+	if err != nil {
+		return err
+	}
+
+	a.Mode = m.Mode
+	a.Tokeniser = tokeniser
+	a.Stemmer = stemmer
+	a.Normalisation = m.Normalisation
+	a.StripAccents = m.StripAccents
+	a.CaseFold = m.CaseFold
+	a.MinNGram = m.MinNGram
+	a.MaxNGram = m.MaxNGram
+	a.Concurrency = m.Concurrency
+
+	return nil
+}
+
+// vocabularyOrEmpty returns vocab or, if it is nil, a new empty vocabulary.
+func vocabularyOrEmpty(vocab map[string]int) map[string]int {
+	if vocab == nil {
+		return make(map[string]int)
+	}
+	return vocab
+}
+
+func (v *vocabularyVectoriser) model() (*vectoriserModel, error) {
+	m := &vectoriserModel{
+		Vocabulary:     v.Vocabulary,
+		Limits:         v.VocabularyLimits,
+		StopWordLookup: v.lookup,
+		StopWords:      v.stopWordList,
+	}
+	return m, v.toModel(m)
+}
+
+func (v *vocabularyVectoriser) setModel(m *vectoriserModel) error {
+	if m.StopWordLookup < regExpLookup || m.StopWordLookup > bloomLookup {
+		return fmt.Errorf("nlpbench: unknown stop word lookup %d", m.StopWordLookup)
+	}
+	v.Vocabulary = vocabularyOrEmpty(m.Vocabulary)
+	v.VocabularyLimits = m.Limits
+	v.lookup = m.StopWordLookup
+	v.SetStopWords(m.StopWords)
+	return v.fromModel(m)
+}
+
+// MarshalBinary encodes the vectoriser, including its fitted vocabulary and
+// configuration, using gob.
+func (v *vocabularyVectoriser) MarshalBinary() ([]byte, error) {
+	return marshalVectoriser(v, gobEncode)
+}
+
+// UnmarshalBinary decodes a vectoriser previously encoded with MarshalBinary.
+func (v *vocabularyVectoriser) UnmarshalBinary(data []byte) error {
+	return unmarshalVectoriser(v, data, gobDecode)
+}
+
+// MarshalJSON encodes the vectoriser, including its fitted vocabulary and
+// configuration, as JSON.
+func (v *vocabularyVectoriser) MarshalJSON() ([]byte, error) {
+	return marshalVectoriser(v, json.Marshal)
+}
+
+// UnmarshalJSON decodes a vectoriser previously encoded with MarshalJSON.
+func (v *vocabularyVectoriser) UnmarshalJSON(data []byte) error {
+	return unmarshalVectoriser(v, data, json.Unmarshal)
+}
+
+// SparseCountVectoriser also records its Format so must provide its own model and
+// setModel, along with the methods calling them, in place of those of the embedded
+// vocabularyVectoriser.
+
+func (v *SparseCountVectoriser) model() (*vectoriserModel, error) {
+	m, err := v.vocabularyVectoriser.model()
+	m.Format = v.Format
+	return m, err
+}
+
+func (v *SparseCountVectoriser) setModel(m *vectoriserModel) error {
+	v.Format = m.Format
+	return v.vocabularyVectoriser.setModel(m)
+}
+
+// MarshalBinary encodes the vectoriser, including its fitted vocabulary and
+// configuration, using gob.
+func (v *SparseCountVectoriser) MarshalBinary() ([]byte, error) {
+	return marshalVectoriser(v, gobEncode)
+}
+
+// UnmarshalBinary decodes a vectoriser previously encoded with MarshalBinary.
+func (v *SparseCountVectoriser) UnmarshalBinary(data []byte) error {
+	return unmarshalVectoriser(v, data, gobDecode)
+}
+
+// MarshalJSON encodes the vectoriser, including its fitted vocabulary and
+// configuration, as JSON.
+func (v *SparseCountVectoriser) MarshalJSON() ([]byte, error) {
+	return marshalVectoriser(v, json.Marshal)
+}
+
+// UnmarshalJSON decodes a vectoriser previously encoded with MarshalJSON.
+func (v *SparseCountVectoriser) UnmarshalJSON(data []byte) error {
+	return unmarshalVectoriser(v, data, json.Unmarshal)
+}
+
+func (v *HashingVectoriser) model() (*vectoriserModel, error) {
+	m := &vectoriserModel{
+		StopWords:     v.stopWordList,
+		NumFeatures:   v.NumFeatures,
+		AlternateSign: v.AlternateSign,
+	}
+	return m, v.toModel(m)
+}
+
+func (v *HashingVectoriser) setModel(m *vectoriserModel) error {
+	v.NumFeatures = m.NumFeatures
+	v.AlternateSign = m.AlternateSign
+	v.SetStopWords(m.StopWords)
+	return v.fromModel(m)
+}
+
+// MarshalBinary encodes the vectoriser configuration using gob.
+func (v *HashingVectoriser) MarshalBinary() ([]byte, error) {
+	return marshalVectoriser(v, gobEncode)
+}
+
+// UnmarshalBinary decodes a vectoriser previously encoded with MarshalBinary.
+func (v *HashingVectoriser) UnmarshalBinary(data []byte) error {
+	return unmarshalVectoriser(v, data, gobDecode)
+}
+
+// MarshalJSON encodes the vectoriser configuration as JSON.
+func (v *HashingVectoriser) MarshalJSON() ([]byte, error) {
+	return marshalVectoriser(v, json.Marshal)
+}
+
+// UnmarshalJSON decodes a vectoriser previously encoded with MarshalJSON.
+func (v *HashingVectoriser) UnmarshalJSON(data []byte) error {
+	return unmarshalVectoriser(v, data, json.Unmarshal)
+}
+
+func (t *TfidfTransformer1) model() *transformerModel {
+	m := &transformerModel{IdfMode: t.Idf}
+	if t.transform != nil {
+		r, _ := t.transform.Dims()
+		m.Weights = make([]float64, r)
+		for i := range m.Weights {
+			m.Weights[i] = t.transform.At(i, i)
+		}
+	}
+	return m
+}
+
+func (t *TfidfTransformer1) setModel(m *transformerModel) error {
+	t.Idf = m.IdfMode
+	t.transform = nil
+	if m.Weights != nil {
+		t.transform = mat64.NewDense(len(m.Weights), len(m.Weights), nil)
+		for i, w := range m.Weights {
+			t.transform.Set(i, i, w)
+		}
+	}
+	return nil
+}
+
+// MarshalBinary encodes the fitted transformer using gob.
+func (t *TfidfTransformer1) MarshalBinary() ([]byte, error) {
+	return marshalTransformer(t, gobEncode)
+}
+
+// UnmarshalBinary decodes a transformer previously encoded with MarshalBinary.
+func (t *TfidfTransformer1) UnmarshalBinary(data []byte) error {
+	return unmarshalTransformer(t, data, gobDecode)
+}
+
+// MarshalJSON encodes the fitted transformer as JSON.
+func (t *TfidfTransformer1) MarshalJSON() ([]byte, error) {
+	return marshalTransformer(t, json.Marshal)
+}
+
+// UnmarshalJSON decodes a transformer previously encoded with MarshalJSON.
+func (t *TfidfTransformer1) UnmarshalJSON(data []byte) error {
+	return unmarshalTransformer(t, data, json.Unmarshal)
+}
+
+func (t *TfidfTransformer2) model() *transformerModel {
+	return &transformerModel{Weights: t.weights, IdfMode: t.Idf}
+}
+
+func (t *TfidfTransformer2) setModel(m *transformerModel) error {
+	t.Idf = m.IdfMode
+	t.weights = m.Weights
+	return nil
+}
+
+// MarshalBinary encodes the fitted transformer using gob.
+func (t *TfidfTransformer2) MarshalBinary() ([]byte, error) {
+	return marshalTransformer(t, gobEncode)
+}
+
+// UnmarshalBinary decodes a transformer previously encoded with MarshalBinary.
+func (t *TfidfTransformer2) UnmarshalBinary(data []byte) error {
+	return unmarshalTransformer(t, data, gobDecode)
+}
+
+// MarshalJSON encodes the fitted transformer as JSON.
+func (t *TfidfTransformer2) MarshalJSON() ([]byte, error) {
+	return marshalTransformer(t, json.Marshal)
+}
+
+// UnmarshalJSON decodes a transformer previously encoded with MarshalJSON.
+func (t *TfidfTransformer2) UnmarshalJSON(data []byte) error {
+	return unmarshalTransformer(t, data, json.Unmarshal)
+}
+
+func (t *TfidfTransformer3) model() *transformerModel {
+	return &transformerModel{Weights: t.weights, IdfMode: t.Idf}
+}
+
+func (t *TfidfTransformer3) setModel(m *transformerModel) error {
+	t.Idf = m.IdfMode
+	t.weights = m.Weights
+	return nil
+}
+
+// MarshalBinary encodes the fitted transformer using gob.
+func (t *TfidfTransformer3) MarshalBinary() ([]byte, error) {
+	return marshalTransformer(t, gobEncode)
+}
+
+// UnmarshalBinary decodes a transformer previously encoded with MarshalBinary.
+func (t *TfidfTransformer3) UnmarshalBinary(data []byte) error {
+	return unmarshalTransformer(t, data, gobDecode)
+}
+
+// MarshalJSON encodes the fitted transformer as JSON.
+func (t *TfidfTransformer3) MarshalJSON() ([]byte, error) {
+	return marshalTransformer(t, json.Marshal)
+}
+
+// UnmarshalJSON decodes a transformer previously encoded with MarshalJSON.
+func (t *TfidfTransformer3) UnmarshalJSON(data []byte) error {
+	return unmarshalTransformer(t, data, json.Unmarshal)
+}
+
+func (t *SparseTfidfTransformer) model() *transformerModel {
+	m := &transformerModel{IdfMode: t.Idf}
+	if dia, ok := t.transform.(*sparse.DIA); ok {
+		m.Weights = dia.Diagonal()
+	}
+	return m
+}
+
+func (t *SparseTfidfTransformer) setModel(m *transformerModel) error {
+	t.Idf = m.IdfMode
+	t.transform = nil
+	if m.Weights != nil {
+		t.transform = sparse.NewDIA(len(m.Weights), m.Weights)
+	}
+	return nil
+}
+
+// MarshalBinary encodes the fitted transformer using gob.
+func (t *SparseTfidfTransformer) MarshalBinary() ([]byte, error) {
+	return marshalTransformer(t, gobEncode)
+}
+
+// UnmarshalBinary decodes a transformer previously encoded with MarshalBinary.
+func (t *SparseTfidfTransformer) UnmarshalBinary(data []byte) error {
+	return unmarshalTransformer(t, data, gobDecode)
+}
+
+// MarshalJSON encodes the fitted transformer as JSON.
+func (t *SparseTfidfTransformer) MarshalJSON() ([]byte, error) {
+	return marshalTransformer(t, json.Marshal)
+}
+
+// UnmarshalJSON decodes a transformer previously encoded with MarshalJSON.
+func (t *SparseTfidfTransformer) UnmarshalJSON(data []byte) error {
+	return unmarshalTransformer(t, data, json.Unmarshal)
+}
+
+func (t *TfTransformer) model() *transformerModel {
+	return &transformerModel{TfMode: t.Mode}
+}
+
+func (t *TfTransformer) setModel(m *transformerModel) error {
+	t.Mode = m.TfMode
+	return nil
+}
+
+// MarshalBinary encodes the transformer configuration using gob.
+func (t *TfTransformer) MarshalBinary() ([]byte, error) {
+	return marshalTransformer(t, gobEncode)
+}
+
+// UnmarshalBinary decodes a transformer previously encoded with MarshalBinary.
+func (t *TfTransformer) UnmarshalBinary(data []byte) error {
+	return unmarshalTransformer(t, data, gobDecode)
+}
+
+// MarshalJSON encodes the transformer configuration as JSON.
+func (t *TfTransformer) MarshalJSON() ([]byte, error) {
+	return marshalTransformer(t, json.Marshal)
+}
+
+// UnmarshalJSON decodes a transformer previously encoded with MarshalJSON.
+func (t *TfTransformer) UnmarshalJSON(data []byte) error {
+	return unmarshalTransformer(t, data, json.Unmarshal)
+}
+
+func (t *SparseTfTransformer) model() *transformerModel {
+	return &transformerModel{TfMode: t.Mode}
+}
+
+func (t *SparseTfTransformer) setModel(m *transformerModel) error {
+	t.Mode = m.TfMode
+	return nil
+}
+
+// MarshalBinary encodes the transformer configuration using gob.
+func (t *SparseTfTransformer) MarshalBinary() ([]byte, error) {
+	return marshalTransformer(t, gobEncode)
+}
+
+// UnmarshalBinary decodes a transformer previously encoded with MarshalBinary.
+func (t *SparseTfTransformer) UnmarshalBinary(data []byte) error {
+	return unmarshalTransformer(t, data, gobDecode)
+}
+
+// MarshalJSON encodes the transformer configuration as JSON.
+func (t *SparseTfTransformer) MarshalJSON() ([]byte, error) {
+	return marshalTransformer(t, json.Marshal)
+}
+
+// UnmarshalJSON decodes a transformer previously encoded with MarshalJSON.
+func (t *SparseTfTransformer) UnmarshalJSON(data []byte) error {
+	return unmarshalTransformer(t, data, json.Unmarshal)
+}
+
+func (t *Normaliser) model() *transformerModel {
+	return &transformerModel{Norm: t.Norm}
+}
+
+func (t *Normaliser) setModel(m *transformerModel) error {
+	t.Norm = m.Norm
+	return nil
+}
+
+// MarshalBinary encodes the transformer configuration using gob.
+func (t *Normaliser) MarshalBinary() ([]byte, error) {
+	return marshalTransformer(t, gobEncode)
+}
+
+// UnmarshalBinary decodes a transformer previously encoded with MarshalBinary.
+func (t *Normaliser) UnmarshalBinary(data []byte) error {
+	return unmarshalTransformer(t, data, gobDecode)
+}
+
+// MarshalJSON encodes the transformer configuration as JSON.
+func (t *Normaliser) MarshalJSON() ([]byte, error) {
+	return marshalTransformer(t, json.Marshal)
+}
+
+// UnmarshalJSON decodes a transformer previously encoded with MarshalJSON.
+func (t *Normaliser) UnmarshalJSON(data []byte) error {
+	return unmarshalTransformer(t, data, json.Unmarshal)
+}
+
+func (t *SparseNormaliser) model() *transformerModel {
+	return &transformerModel{Norm: t.Norm}
+}
+
+func (t *SparseNormaliser) setModel(m *transformerModel) error {
+	t.Norm = m.Norm
+	return nil
+}
+
+// MarshalBinary encodes the transformer configuration using gob.
+func (t *SparseNormaliser) MarshalBinary() ([]byte, error) {
+	return marshalTransformer(t, gobEncode)
+}
+
+// UnmarshalBinary decodes a transformer previously encoded with MarshalBinary.
+func (t *SparseNormaliser) UnmarshalBinary(data []byte) error {
+	return unmarshalTransformer(t, data, gobDecode)
+}
+
+// MarshalJSON encodes the transformer configuration as JSON.
+func (t *SparseNormaliser) MarshalJSON() ([]byte, error) {
+	return marshalTransformer(t, json.Marshal)
+}
+
+// UnmarshalJSON decodes a transformer previously encoded with MarshalJSON.
+func (t *SparseNormaliser) UnmarshalJSON(data []byte) error {
+	return unmarshalTransformer(t, data, json.Unmarshal)
+}
+
+func (t *BM25Transformer) model() *transformerModel {
+	return &transformerModel{Weights: t.idf, K1: t.K1, B: t.B, AvgDocLen: t.avgDocLen}
+}
+
+func (t *BM25Transformer) setModel(m *transformerModel) error {
+	t.idf = m.Weights
+	t.K1, t.B, t.avgDocLen = m.K1, m.B, m.AvgDocLen
+	return nil
+}
+
+// MarshalBinary encodes the fitted transformer using gob.
+func (t *BM25Transformer) MarshalBinary() ([]byte, error) {
+	return marshalTransformer(t, gobEncode)
+}
+
+// UnmarshalBinary decodes a transformer previously encoded with MarshalBinary.
+func (t *BM25Transformer) UnmarshalBinary(data []byte) error {
+	return unmarshalTransformer(t, data, gobDecode)
+}
+
+// MarshalJSON encodes the fitted transformer as JSON.
+func (t *BM25Transformer) MarshalJSON() ([]byte, error) {
+	return marshalTransformer(t, json.Marshal)
+}
+
+// UnmarshalJSON decodes a transformer previously encoded with MarshalJSON.
+func (t *BM25Transformer) UnmarshalJSON(data []byte) error {
+	return unmarshalTransformer(t, data, json.Unmarshal)
+}
+
+func (t *SparseBM25Transformer) model() *transformerModel {
+	return &transformerModel{Weights: t.idf, K1: t.K1, B: t.B, AvgDocLen: t.avgDocLen}
+}
+
+func (t *SparseBM25Transformer) setModel(m *transformerModel) error {
+	t.idf = m.Weights
+	t.K1, t.B, t.avgDocLen = m.K1, m.B, m.AvgDocLen
+	return nil
+}
+
+// MarshalBinary encodes the fitted transformer using gob.
+func (t *SparseBM25Transformer) MarshalBinary() ([]byte, error) {
+	return marshalTransformer(t, gobEncode)
+}
+
+// UnmarshalBinary decodes a transformer previously encoded with MarshalBinary.
+func (t *SparseBM25Transformer) UnmarshalBinary(data []byte) error {
+	return unmarshalTransformer(t, data, gobDecode)
+}
+
+// MarshalJSON encodes the fitted transformer as JSON.
+func (t *SparseBM25Transformer) MarshalJSON() ([]byte, error) {
+	return marshalTransformer(t, json.Marshal)
+}
+
+// UnmarshalJSON decodes a transformer previously encoded with MarshalJSON.
+func (t *SparseBM25Transformer) UnmarshalJSON(data []byte) error {
+	return unmarshalTransformer(t, data, json.Unmarshal)
+}
@@ -0,0 +1,227 @@
+package nlpbench
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+var serialisationDocs = []string{
+	"The quick brown fox jumped over the lazy dog",
+	"the lazy dog slept in the sun while the fox ran away",
+	"a big red space shuttle launched into orbit above the sun",
+}
+
+// codec encodes and decodes a value so round trips can be tested for each encoding.
+type codec struct {
+	name   string
+	encode func(v interface{}) ([]byte, error)
+	decode func(data []byte, v interface{}) error
+}
+
+var codecs = []codec{
+	{
+		name: "Gob",
+		encode: func(v interface{}) ([]byte, error) {
+			return v.(encoding.BinaryMarshaler).MarshalBinary()
+		},
+		decode: func(data []byte, v interface{}) error {
+			return v.(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+		},
+	},
+	{
+		name:   "JSON",
+		encode: json.Marshal,
+		decode: json.Unmarshal,
+	},
+}
+
+// newOfType returns a new zero value of the same (pointer) type as v.
+func newOfType(v interface{}) interface{} {
+	return reflect.New(reflect.TypeOf(v).Elem()).Interface()
+}
+
+func TestVectoriserSerialisation(t *testing.T) {
+	type variant struct {
+		name      string
+		configure func(v Vectoriser)
+	}
+	variants := []variant{
+		{name: "Default", configure: func(v Vectoriser) {}},
+		{name: "NGrams", configure: func(v Vectoriser) {
+			a := analyserOf(v)
+			a.MinNGram, a.MaxNGram = 1, 2
+			a.Tokeniser = &ScannerTokeniser{}
+		}},
+		{name: "Stemmed", configure: func(v Vectoriser) {
+			analyserOf(v).Stemmer = &Porter2Stemmer{}
+		}},
+		{name: "CharWordBoundary", configure: func(v Vectoriser) {
+			a := analyserOf(v)
+			a.Mode = CharWordBoundaryAnalyser
+			a.MinNGram, a.MaxNGram = 2, 4
+			a.Tokeniser = &UnicodeTokeniser{}
+		}},
+	}
+
+	for _, c := range codecs {
+		for _, f := range Vectorisers {
+			for _, removeStopwords := range []bool{false, true} {
+				for _, variant := range variants {
+					name := f.Name + "/" + c.name + "/" + variant.name
+					if removeStopwords {
+						name += "/StopWords"
+					}
+					t.Run(name, func(t *testing.T) {
+						vect := f.New(removeStopwords)
+						variant.configure(vect)
+						switch vect := vect.(type) {
+						case *SparseCountVectoriser:
+							vect.Format = CSCFormat
+							vect.MaxFeatures = 20
+						case *HashingVectoriser:
+							vect.NumFeatures = 1 << 10
+							vect.AlternateSign = true
+						}
+
+						want, err := vect.FitTransform(serialisationDocs...)
+						if err != nil {
+							t.Fatalf("Failed to fit and transform: %v", err)
+						}
+
+						data, err := c.encode(vect)
+						if err != nil {
+							t.Fatalf("Failed to encode: %v", err)
+						}
+						decoded := newOfType(vect)
+						if err := c.decode(data, decoded); err != nil {
+							t.Fatalf("Failed to decode: %v", err)
+						}
+
+						// the stop word lookup of the vectoriser should be restored along
+						// with the stop words
+						wantLookup := reflect.ValueOf(vect).Elem().FieldByName("lookup")
+						gotLookup := reflect.ValueOf(decoded).Elem().FieldByName("lookup")
+						if wantLookup.IsValid() && gotLookup.Int() != wantLookup.Int() {
+							t.Errorf("Expected stop word lookup %d but got %d", wantLookup.Int(), gotLookup.Int())
+						}
+
+						got, err := decoded.(Vectoriser).Transform(serialisationDocs...)
+						if err != nil {
+							t.Fatalf("Failed to transform: %v", err)
+						}
+
+						if reflect.TypeOf(got) != reflect.TypeOf(want) {
+							t.Errorf("Expected matrix of type %T but got %T", want, got)
+						}
+						if !mat64.Equal(got, want) {
+							t.Errorf("Expected\n%v\nbut got\n%v", mat64.Formatted(want), mat64.Formatted(got))
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+func TestTransformerSerialisation(t *testing.T) {
+	vect := NewCountVectoriser2(true)
+	mat, _ := vect.FitTransform(serialisationDocs...)
+
+	transformers := []struct {
+		name        string
+		transformer interface{}
+	}{
+		{name: "TfidfTransformer1", transformer: &TfidfTransformer1{}},
+		{name: "TfidfTransformer2", transformer: &TfidfTransformer2{Idf: SmoothIdf}},
+		{name: "TfidfTransformer3", transformer: &TfidfTransformer3{Idf: MaxIdf}},
+		{name: "TfTransformer", transformer: NewTfTransformer(AugmentedTf)},
+		{name: "Normaliser", transformer: NewNormaliser(L1Norm)},
+		{name: "BM25Transformer", transformer: NewBM25Transformer()},
+		{name: "SparseBM25Transformer", transformer: &SparseBM25Transformer{K1: 2, B: 0.5}},
+		{name: "SparseNormaliser", transformer: NewSparseNormaliser(MaxNorm)},
+		{name: "SparseTfTransformer", transformer: NewSparseTfTransformer(SublinearTf)},
+		{name: "SparseTfidfTransformer", transformer: &SparseTfidfTransformer{Idf: StandardIdf}},
+	}
+
+	for _, c := range codecs {
+		for _, test := range transformers {
+			t.Run(test.name+"/"+c.name, func(t *testing.T) {
+				// dense transformers are serialised directly rather than through the
+				// AsSparseTransformer adapter so that their own marshalling is used
+				transform := func(t interface{}, m mat64.Matrix) (mat64.Matrix, error) {
+					if dense, ok := t.(Transformer); ok {
+						return dense.Transform(m)
+					}
+					return t.(SparseTransformer).Transform(m)
+				}
+
+				var trans interface{}
+				switch tr := test.transformer.(type) {
+				case Transformer:
+					trans = tr.Fit(mat)
+				case SparseTransformer:
+					trans = tr.Fit(mat)
+				}
+				want, err := transform(trans, mat)
+				if err != nil {
+					t.Fatalf("Failed to transform: %v", err)
+				}
+
+				data, err := c.encode(trans)
+				if err != nil {
+					t.Fatalf("Failed to encode: %v", err)
+				}
+				decoded := newOfType(trans)
+				if err := c.decode(data, decoded); err != nil {
+					t.Fatalf("Failed to decode: %v", err)
+				}
+
+				got, err := transform(decoded, mat)
+				if err != nil {
+					t.Fatalf("Failed to transform: %v", err)
+				}
+
+				if !mat64.EqualApprox(got, want, 1e-12) {
+					t.Errorf("Expected\n%v\nbut got\n%v", mat64.Formatted(want), mat64.Formatted(got))
+				}
+			})
+		}
+	}
+}
+
+func TestSerialisationVersion(t *testing.T) {
+	vect := NewCountVectoriser2(true)
+	vect.Fit(serialisationDocs...)
+
+	data, err := json.Marshal(vect)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	data = []byte(strings.Replace(string(data), `"version":1`, `"version":99`, 1))
+
+	var decoded CountVectoriser2
+	if err := json.Unmarshal(data, &decoded); err == nil {
+		t.Errorf("Expected error decoding unsupported version but got none")
+	}
+}
+
+func TestSerialisationStopWordLookup(t *testing.T) {
+	vect := NewCountVectoriser5(true)
+	vect.Fit(serialisationDocs...)
+
+	data, err := json.Marshal(vect)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	data = []byte(strings.Replace(string(data), `"stopWordLookup":4`, `"stopWordLookup":99`, 1))
+
+	var decoded CountVectoriser5
+	if err := json.Unmarshal(data, &decoded); err == nil {
+		t.Errorf("Expected error decoding unknown stop word lookup but got none")
+	}
+}
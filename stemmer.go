@@ -0,0 +1,376 @@
+package nlpbench
+
+import (
+	"strings"
+)
+
+// Stemmer is implemented by types that reduce inflected or derived words to a common
+// stem, so that for example "launch", "launched" and "launches" are counted as the same
+// term.  Stemmers expect words to have already been converted to lower case by the
+// Tokeniser.
+type Stemmer interface {
+	Stem(word string) string
+}
+
+// Porter2Stemmer stems English words using the Porter2 (Snowball English) stemming
+// algorithm described at http://snowball.tartarus.org/algorithms/english/stemmer.html.
+// Only the letters a-z are treated as letters, any other bytes (including the bytes of
+// non ASCII runes) are treated as consonants.
+type Porter2Stemmer struct{}
+
+// porter2Exceptions are words with irregular stems that are returned directly rather
+// than being stemmed by the algorithm.
+var porter2Exceptions = map[string]string{
+	"skis":   "ski",
+	"skies":  "sky",
+	"dying":  "die",
+	"lying":  "lie",
+	"tying":  "tie",
+	"idly":   "idl",
+	"gently": "gentl",
+	"ugly":   "ugli",
+	"early":  "earli",
+	"only":   "onli",
+	"singly": "singl",
+	"sky":    "sky",
+	"news":   "news",
+	"howe":   "howe",
+	"atlas":  "atlas",
+	"cosmos": "cosmos",
+	"bias":   "bias",
+	"andes":  "andes",
+}
+
+// porter2Invariants are words that are left unchanged if they result from Step 1a.
+var porter2Invariants = map[string]bool{
+	"inning":  true,
+	"outing":  true,
+	"canning": true,
+	"herring": true,
+	"earring": true,
+	"proceed": true,
+	"exceed":  true,
+	"succeed": true,
+}
+
+// porter2Rule replaces suffix with replacement if the condition cond, applied to the
+// word with the suffix removed, is met.  A nil cond is always met.
+type porter2Rule struct {
+	suffix      string
+	replacement string
+	cond        func(w *porter2Word, stem int) bool
+}
+
+// Rules for Steps 2 to 4.  Within each step, rules are ordered longest suffix first so
+// that the first rule with a matching suffix is for the longest matching suffix.
+var (
+	porter2Step2 = []porter2Rule{
+		{"ational", "ate", nil},
+		{"fulness", "ful", nil},
+		{"iveness", "ive", nil},
+		{"ization", "ize", nil},
+		{"ousness", "ous", nil},
+		{"biliti", "ble", nil},
+		{"lessli", "less", nil},
+		{"tional", "tion", nil},
+		{"alism", "al", nil},
+		{"aliti", "al", nil},
+		{"ation", "ate", nil},
+		{"entli", "ent", nil},
+		{"fulli", "ful", nil},
+		{"iviti", "ive", nil},
+		{"ousli", "ous", nil},
+		{"abli", "able", nil},
+		{"alli", "al", nil},
+		{"anci", "ance", nil},
+		{"ator", "ate", nil},
+		{"enci", "ence", nil},
+		{"izer", "ize", nil},
+		{"bli", "ble", nil},
+		{"ogi", "og", func(w *porter2Word, stem int) bool { return stem > 0 && w.b[stem-1] == 'l' }},
+		{"li", "", func(w *porter2Word, stem int) bool { return stem > 0 && isValidLiEnding(w.b[stem-1]) }},
+	}
+
+	porter2Step3 = []porter2Rule{
+		{"ational", "ate", nil},
+		{"tional", "tion", nil},
+		{"alize", "al", nil},
+		{"ative", "", func(w *porter2Word, stem int) bool { return stem >= w.r2 }},
+		{"icate", "ic", nil},
+		{"iciti", "ic", nil},
+		{"ical", "ic", nil},
+		{"ness", "", nil},
+		{"ful", "", nil},
+	}
+
+	porter2Step4 = []porter2Rule{
+		{"ement", "", nil},
+		{"able", "", nil},
+		{"ance", "", nil},
+		{"ence", "", nil},
+		{"ible", "", nil},
+		{"ment", "", nil},
+		{"ant", "", nil},
+		{"ate", "", nil},
+		{"ent", "", nil},
+		{"ion", "", func(w *porter2Word, stem int) bool {
+			return stem > 0 && (w.b[stem-1] == 's' || w.b[stem-1] == 't')
+		}},
+		{"ism", "", nil},
+		{"iti", "", nil},
+		{"ive", "", nil},
+		{"ize", "", nil},
+		{"ous", "", nil},
+		{"al", "", nil},
+		{"er", "", nil},
+		{"ic", "", nil},
+	}
+)
+
+// porter2Word holds a word being stemmed along with the start of its R1 and R2 regions.
+type porter2Word struct {
+	b      []byte
+	r1, r2 int
+}
+
+// Stem returns the Porter2 stem of the lower case word.
+func (s *Porter2Stemmer) Stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	if stem, ok := porter2Exceptions[word]; ok {
+		return stem
+	}
+
+	w := &porter2Word{b: []byte(strings.TrimPrefix(word, "'"))}
+	w.markYs()
+	w.r1, w.r2 = w.regions()
+
+	w.step0()
+	w.step1a()
+	if porter2Invariants[string(w.b)] {
+		return string(w.b)
+	}
+	w.step1b()
+	w.step1c()
+	w.applyRules(porter2Step2, w.r1)
+	w.applyRules(porter2Step3, w.r1)
+	w.applyRules(porter2Step4, w.r2)
+	w.step5()
+
+	for i, c := range w.b {
+		if c == 'Y' {
+			w.b[i] = 'y'
+		}
+	}
+
+	return string(w.b)
+}
+
+// isVowel returns true if c is a vowel.  Y marks a 'y' that is treated as a consonant.
+func isVowel(c byte) bool {
+	switch c {
+	case 'a', 'e', 'i', 'o', 'u', 'y':
+		return true
+	}
+	return false
+}
+
+// isDouble returns true if the pair of letters is one of the doubles removed in Step 1b.
+func isDouble(a, b byte) bool {
+	if a != b {
+		return false
+	}
+	switch a {
+	case 'b', 'd', 'f', 'g', 'm', 'n', 'p', 'r', 't':
+		return true
+	}
+	return false
+}
+
+// isValidLiEnding returns true if c may precede the suffix "li" removed in Step 2.
+func isValidLiEnding(c byte) bool {
+	switch c {
+	case 'c', 'd', 'e', 'g', 'h', 'k', 'm', 'n', 'r', 't':
+		return true
+	}
+	return false
+}
+
+// markYs replaces an initial 'y', and any 'y' following a vowel, with 'Y' so that it is
+// treated as a consonant.
+func (w *porter2Word) markYs() {
+	for i, c := range w.b {
+		if c == 'y' && (i == 0 || isVowel(w.b[i-1])) {
+			w.b[i] = 'Y'
+		}
+	}
+}
+
+// regions returns the start of the R1 and R2 regions of the word.  R1 is the region
+// after the first non-vowel following a vowel (or after one of the prefixes gener,
+// commun or arsen) and R2 is the same region within R1.
+func (w *porter2Word) regions() (int, int) {
+	r1 := -1
+	for _, prefix := range []string{"gener", "commun", "arsen"} {
+		if strings.HasPrefix(string(w.b), prefix) {
+			r1 = len(prefix)
+			break
+		}
+	}
+	if r1 < 0 {
+		r1 = w.regionAfter(0)
+	}
+	return r1, w.regionAfter(r1)
+}
+
+// regionAfter returns the index following the first non-vowel that follows a vowel at
+// or after start, or the length of the word if there is none.
+func (w *porter2Word) regionAfter(start int) int {
+	for i := start + 1; i < len(w.b); i++ {
+		if !isVowel(w.b[i]) && isVowel(w.b[i-1]) {
+			return i + 1
+		}
+	}
+	return len(w.b)
+}
+
+// hasSuffix returns true if the word ends with suffix.
+func (w *porter2Word) hasSuffix(suffix string) bool {
+	return len(w.b) >= len(suffix) && string(w.b[len(w.b)-len(suffix):]) == suffix
+}
+
+// replace replaces the final n bytes of the word with replacement.
+func (w *porter2Word) replace(n int, replacement string) {
+	w.b = append(w.b[:len(w.b)-n], replacement...)
+}
+
+// containsVowel returns true if w.b[:end] contains a vowel.
+func (w *porter2Word) containsVowel(end int) bool {
+	for _, c := range w.b[:end] {
+		if isVowel(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// endsShortSyllable returns true if w.b[:end] ends with a short syllable, that is either
+// a non-vowel followed by a vowel followed by a non-vowel other than w, x or Y, or a
+// vowel at the beginning of the word followed by a non-vowel.
+func (w *porter2Word) endsShortSyllable(end int) bool {
+	if end == 2 {
+		return isVowel(w.b[0]) && !isVowel(w.b[1])
+	}
+	if end < 3 {
+		return false
+	}
+	c := w.b[end-1]
+	return !isVowel(w.b[end-3]) && isVowel(w.b[end-2]) && !isVowel(c) && c != 'w' && c != 'x' && c != 'Y'
+}
+
+// isShort returns true if the word ends in a short syllable and R1 is empty.
+func (w *porter2Word) isShort() bool {
+	return w.r1 >= len(w.b) && w.endsShortSyllable(len(w.b))
+}
+
+// step0 removes the longest of the suffixes ', 's and 's'.
+func (w *porter2Word) step0() {
+	for _, suffix := range []string{"'s'", "'s", "'"} {
+		if w.hasSuffix(suffix) {
+			w.replace(len(suffix), "")
+			return
+		}
+	}
+}
+
+// step1a handles plurals ending in s.
+func (w *porter2Word) step1a() {
+	switch {
+	case w.hasSuffix("sses"):
+		w.replace(2, "")
+	case w.hasSuffix("ied"), w.hasSuffix("ies"):
+		if len(w.b) > 4 {
+			w.replace(3, "i")
+		} else {
+			w.replace(3, "ie")
+		}
+	case w.hasSuffix("us"), w.hasSuffix("ss"):
+	case w.hasSuffix("s"):
+		if w.containsVowel(len(w.b) - 2) {
+			w.replace(1, "")
+		}
+	}
+}
+
+// step1b handles the suffixes eed, ed and ing along with their adverb forms.
+func (w *porter2Word) step1b() {
+	for _, suffix := range []string{"eedly", "eed"} {
+		if w.hasSuffix(suffix) {
+			if len(w.b)-len(suffix) >= w.r1 {
+				w.replace(len(suffix), "ee")
+			}
+			return
+		}
+	}
+
+	for _, suffix := range []string{"ingly", "edly", "ing", "ed"} {
+		if !w.hasSuffix(suffix) {
+			continue
+		}
+		if !w.containsVowel(len(w.b) - len(suffix)) {
+			return
+		}
+		w.replace(len(suffix), "")
+
+		n := len(w.b)
+		switch {
+		case w.hasSuffix("at"), w.hasSuffix("bl"), w.hasSuffix("iz"):
+			w.replace(0, "e")
+		case n >= 2 && isDouble(w.b[n-2], w.b[n-1]):
+			w.replace(1, "")
+		case w.isShort():
+			w.replace(0, "e")
+		}
+		return
+	}
+}
+
+// step1c replaces a final y (or Y) with i if it follows a non-vowel that is not the
+// first letter of the word.
+func (w *porter2Word) step1c() {
+	n := len(w.b)
+	if n > 2 && (w.b[n-1] == 'y' || w.b[n-1] == 'Y') && !isVowel(w.b[n-2]) {
+		w.b[n-1] = 'i'
+	}
+}
+
+// applyRules applies the rule for the longest matching suffix, provided the suffix lies
+// within the region starting at region and the rule's condition is met.
+func (w *porter2Word) applyRules(rules []porter2Rule, region int) {
+	for _, rule := range rules {
+		if !w.hasSuffix(rule.suffix) {
+			continue
+		}
+		stem := len(w.b) - len(rule.suffix)
+		if stem >= region && (rule.cond == nil || rule.cond(w, stem)) {
+			w.replace(len(rule.suffix), rule.replacement)
+		}
+		return
+	}
+}
+
+// step5 removes a final e or the second of a final double l.
+func (w *porter2Word) step5() {
+	n := len(w.b)
+	switch {
+	case w.hasSuffix("e"):
+		if n-1 >= w.r2 || (n-1 >= w.r1 && !w.endsShortSyllable(n-1)) {
+			w.replace(1, "")
+		}
+	case w.hasSuffix("l"):
+		if n-1 >= w.r2 && n >= 2 && w.b[n-2] == 'l' {
+			w.replace(1, "")
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package nlpbench
+
+import (
+	"testing"
+)
+
+func TestPorter2Stemmer(t *testing.T) {
+	tests := map[string]string{
+		"a":             "a",
+		"at":            "at",
+		"cats":          "cat",
+		"gas":           "gas",
+		"this":          "this",
+		"caresses":      "caress",
+		"ponies":        "poni",
+		"ties":          "tie",
+		"cries":         "cri",
+		"agreed":        "agre",
+		"plastered":     "plaster",
+		"motoring":      "motor",
+		"sing":          "sing",
+		"hoped":         "hope",
+		"hopping":       "hop",
+		"tanned":        "tan",
+		"falling":       "fall",
+		"hissing":       "hiss",
+		"fizzed":        "fizz",
+		"failing":       "fail",
+		"filing":        "file",
+		"conflated":     "conflat",
+		"troubled":      "troubl",
+		"sized":         "size",
+		"happy":         "happi",
+		"say":           "say",
+		"by":            "by",
+		"launch":        "launch",
+		"launched":      "launch",
+		"launches":      "launch",
+		"launching":     "launch",
+		"relational":    "relat",
+		"conditional":   "condit",
+		"rational":      "ration",
+		"digitizer":     "digit",
+		"operator":      "oper",
+		"feudalism":     "feudal",
+		"decisiveness":  "decis",
+		"hopefulness":   "hope",
+		"callousness":   "callous",
+		"generate":      "generat",
+		"consign":       "consign",
+		"consigned":     "consign",
+		"consignment":   "consign",
+		"consistency":   "consist",
+		"consistently":  "consist",
+		"consolation":   "consol",
+		"consolatory":   "consolatori",
+		"consolidating": "consolid",
+		"consolingly":   "consol",
+		"conspicuously": "conspicu",
+		"conspiracy":    "conspiraci",
+		"conspirators":  "conspir",
+		"constables":    "constabl",
+		"constancy":     "constanc",
+		"knackeries":    "knackeri",
+		"knightly":      "knight",
+		"knocked":       "knock",
+		"dying":         "die",
+		"news":          "news",
+		"skies":         "sky",
+		"proceed":       "proceed",
+		"succeeding":    "succeed",
+		"inning":        "inning",
+		"innings":       "inning",
+		"yesterday":     "yesterday",
+		"boy's":         "boy",
+	}
+
+	stemmer := &Porter2Stemmer{}
+	for word, want := range tests {
+		if got := stemmer.Stem(word); got != want {
+			t.Errorf("Stem(%q): expected %q but got %q", word, want, got)
+		}
+	}
+}
+
+// Benchmark vocabulary size and Fit cost with and without stemming
+func BenchmarkStemmingFit(b *testing.B) {
+	files := Load("sci.space", "sci.electronics")
+
+	stemmers := []struct {
+		name    string
+		stemmer Stemmer
+	}{
+		{"None", nil},
+		{"Porter2", &Porter2Stemmer{}},
+	}
+
+	for _, s := range stemmers {
+		b.Run(s.name, func(b *testing.B) {
+			vect := NewDOKCountVectoriser1(true)
+			vect.Stemmer = s.stemmer
+
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				vect.Fit(files...)
+			}
+			b.ReportMetric(float64(len(vect.Vocabulary)), "terms")
+		})
+	}
+}
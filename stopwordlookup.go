@@ -0,0 +1,300 @@
+package nlpbench
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/golang-collections/collections/trie"
+)
+
+// Alternative stop word lookup data structures to compare against the regular
+// expression, map and trie based lookups.  Each constructor returns nil if there are no
+// stop words and the has method of each type may be called on a nil pointer, in which
+// case it always returns false.  The regular expression, map and trie based lookups are
+// wrapped by regExpStopWords, mapStopWords and trieStopWords so that all the lookups
+// share the stopWordSet interface.
+
+// stopWordSet is implemented by each of the stop word lookups so that they may be used
+// interchangeably by the vectorisers that embed vocabularyVectoriser.
+type stopWordSet interface {
+	has(word string) bool
+}
+
+// stopWordLookupType identifies the data structure a vectoriser uses to look up stop
+// words.
+type stopWordLookupType int
+
+const (
+	regExpLookup stopWordLookupType = iota
+	mapLookup
+	trieLookup
+	perfectHashLookup
+	sortedLookup
+	lengthBucketedLookup
+	bloomLookup
+)
+
+// build returns a stopWordSet of the words using the data structure identified by l.
+func (l stopWordLookupType) build(words []string) stopWordSet {
+	switch l {
+	case mapLookup:
+		return mapStopWords(newMapStopWords(words))
+	case trieLookup:
+		return trieStopWords{newTrieStopWords(words)}
+	case perfectHashLookup:
+		return newPerfectHashStopWords(words)
+	case sortedLookup:
+		return newSortedStopWords(words)
+	case lengthBucketedLookup:
+		return newLengthBucketedStopWords(words)
+	case bloomLookup:
+		return newBloomStopWords(words)
+	}
+	return regExpStopWords{newRegExpStopWords(words)}
+}
+
+// regExpStopWords is a single regular expression matching any of the stop words.
+type regExpStopWords struct {
+	re *regexp.Regexp
+}
+
+// has returns true if word is a stop word.
+func (r regExpStopWords) has(word string) bool {
+	return r.re != nil && r.re.MatchString(word)
+}
+
+// mapStopWords is a map of stop words.
+type mapStopWords map[string]bool
+
+// has returns true if word is a stop word.
+func (m mapStopWords) has(word string) bool {
+	return m[word]
+}
+
+// trieStopWords is a trie of stop words.
+type trieStopWords struct {
+	t *trie.Trie
+}
+
+// has returns true if word is a stop word.
+func (t trieStopWords) has(word string) bool {
+	return t.t != nil && t.t.Has(word)
+}
+
+// seededHash returns the FNV-1a hash of s, perturbed by seed and finished with an
+// avalanche step so that different seeds produce independent, well distributed hashes.
+func seededHash(s string, seed uint32) uint32 {
+	h := uint32(2166136261) ^ seed*16777619
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= 16777619
+	}
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+// perfectHashStopWords is a minimal perfect hash table of stop words built using the
+// hash and displace algorithm.  Words are first hashed into buckets and then a seed is
+// found for each bucket such that the words in the bucket hash, using that seed, to
+// distinct empty slots.  Lookups therefore require exactly two hashes and a single
+// string comparison.
+type perfectHashStopWords struct {
+	seeds []uint32
+	slots []string
+}
+
+// newPerfectHashStopWords builds a minimal perfect hash table of the stop words.  Any
+// duplicate words are removed first as no seed could place two copies of a word, which
+// always hash to the same slot, in distinct slots.
+func newPerfectHashStopWords(words []string) *perfectHashStopWords {
+	words = uniqueStopWords(words)
+	if len(words) == 0 {
+		return nil
+	}
+
+	n := uint32(len(words))
+	buckets := make([][]string, n)
+	for _, word := range words {
+		b := seededHash(word, 0) % n
+		buckets[b] = append(buckets[b], word)
+	}
+
+	// place the largest buckets first while there are most empty slots
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(buckets[order[i]]) > len(buckets[order[j]])
+	})
+
+	p := &perfectHashStopWords{
+		seeds: make([]uint32, n),
+		slots: make([]string, n),
+	}
+	used := make([]bool, n)
+	taken := make([]uint32, 0, 8)
+
+	for _, b := range order {
+		if len(buckets[b]) == 0 {
+			break
+		}
+		for seed := uint32(1); ; seed++ {
+			taken = taken[:0]
+			for _, word := range buckets[b] {
+				slot := seededHash(word, seed) % n
+				if used[slot] {
+					break
+				}
+				used[slot] = true
+				taken = append(taken, slot)
+			}
+			if len(taken) == len(buckets[b]) {
+				for i, slot := range taken {
+					p.slots[slot] = buckets[b][i]
+				}
+				p.seeds[b] = seed
+				break
+			}
+			// collision so release any slots taken with this seed and try the next
+			for _, slot := range taken {
+				used[slot] = false
+			}
+		}
+	}
+
+	return p
+}
+
+// has returns true if word is a stop word.
+func (p *perfectHashStopWords) has(word string) bool {
+	if p == nil {
+		return false
+	}
+	n := uint32(len(p.slots))
+	seed := p.seeds[seededHash(word, 0)%n]
+	return p.slots[seededHash(word, seed)%n] == word
+}
+
+// sortedStopWords is a sorted slice of stop words searched using binary search.
+type sortedStopWords []string
+
+// newSortedStopWords returns a sorted copy of the stop words.
+func newSortedStopWords(words []string) sortedStopWords {
+	if len(words) == 0 {
+		return nil
+	}
+
+	sorted := append(sortedStopWords(nil), words...)
+	sort.Strings(sorted)
+
+	return sorted
+}
+
+// has returns true if word is a stop word.
+func (s sortedStopWords) has(word string) bool {
+	i := sort.SearchStrings(s, word)
+	return i < len(s) && s[i] == word
+}
+
+// lengthBucketedStopWords groups stop words into buckets by length, each of which is
+// sorted.  This mirrors the code the Go compiler generates for a switch statement over
+// constant strings, which first switches on the length of the string and then performs
+// a binary search over the cases of that length, but allows the list of stop words to
+// be chosen at runtime.
+type lengthBucketedStopWords [][]string
+
+// newLengthBucketedStopWords groups the stop words into sorted buckets by length.
+func newLengthBucketedStopWords(words []string) lengthBucketedStopWords {
+	if len(words) == 0 {
+		return nil
+	}
+
+	var buckets lengthBucketedStopWords
+	for _, word := range words {
+		for len(word) >= len(buckets) {
+			buckets = append(buckets, nil)
+		}
+		buckets[len(word)] = append(buckets[len(word)], word)
+	}
+	for _, bucket := range buckets {
+		sort.Strings(bucket)
+	}
+
+	return buckets
+}
+
+// has returns true if word is a stop word.
+func (l lengthBucketedStopWords) has(word string) bool {
+	if len(word) >= len(l) {
+		return false
+	}
+	bucket := l[len(word)]
+	i := sort.SearchStrings(bucket, word)
+	return i < len(bucket) && bucket[i] == word
+}
+
+// bloomBitsPerWord is the number of bits in the bloom filter per stop word and
+// bloomHashes the number of hash functions.  Together these give a false positive rate
+// of around 3%.
+const (
+	bloomBitsPerWord = 8
+	bloomHashes      = 3
+)
+
+// bloomStopWords uses a bloom filter as a prefilter in front of a map of stop words.
+// As most words in a document are not stop words, most lookups are rejected by the
+// filter, which is small enough to remain in cache, without hashing the word into the
+// map.  Words passing the filter are confirmed against the map to exclude false
+// positives.
+type bloomStopWords struct {
+	bits  []uint64
+	mask  uint32
+	words map[string]bool
+}
+
+// newBloomStopWords builds a bloom filter and map of the stop words.
+func newBloomStopWords(words []string) *bloomStopWords {
+	if len(words) == 0 {
+		return nil
+	}
+
+	size := uint32(64)
+	for size < uint32(len(words)*bloomBitsPerWord) {
+		size <<= 1
+	}
+
+	b := &bloomStopWords{
+		bits:  make([]uint64, size/64),
+		mask:  size - 1,
+		words: newMapStopWords(words),
+	}
+	for _, word := range words {
+		h1, h2 := seededHash(word, 0), seededHash(word, 1)
+		for i := uint32(0); i < bloomHashes; i++ {
+			bit := (h1 + i*h2) & b.mask
+			b.bits[bit/64] |= 1 << (bit % 64)
+		}
+	}
+
+	return b
+}
+
+// has returns true if word is a stop word.
+func (b *bloomStopWords) has(word string) bool {
+	if b == nil {
+		return false
+	}
+	h1, h2 := seededHash(word, 0), seededHash(word, 1)
+	for i := uint32(0); i < bloomHashes; i++ {
+		bit := (h1 + i*h2) & b.mask
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return b.words[word]
+}
@@ -0,0 +1,96 @@
+package nlpbench
+
+import (
+	"strings"
+	"testing"
+)
+
+// stopWordLookup pairs a stop word lookup strategy with a name.
+type stopWordLookup struct {
+	name string
+	has  func(string) bool
+}
+
+// stopWordLookups returns each of the stop word lookup strategies built from words.
+func stopWordLookups(words []string) []stopWordLookup {
+	words = uniqueStopWords(words)
+
+	re := newRegExpStopWords(words)
+	m := newMapStopWords(words)
+	tr := newTrieStopWords(words)
+	perfect := newPerfectHashStopWords(words)
+	sorted := newSortedStopWords(words)
+	bucketed := newLengthBucketedStopWords(words)
+	bloom := newBloomStopWords(words)
+
+	return []stopWordLookup{
+		{"Regex", func(w string) bool { return re.MatchString(w) }},
+		{"Map", func(w string) bool { return m[w] }},
+		{"Trie", func(w string) bool { return tr.Has(w) }},
+		{"PerfectHash", perfect.has},
+		{"SortedSlice", sorted.has},
+		{"LengthBucketed", bucketed.has},
+		{"Bloom", bloom.has},
+	}
+}
+
+func TestStopWordLookups(t *testing.T) {
+	others := strings.Fields("fox dog abov aboutt thick thickv zebra space shuttle x qu é")
+
+	for language, words := range stopWordLists {
+		for _, lookup := range stopWordLookups(words) {
+			for _, word := range words {
+				if !lookup.has(word) {
+					t.Errorf("%s/%s: expected %q to be a stop word", language, lookup.name, word)
+				}
+			}
+			for _, word := range others {
+				if lookup.has(word) && !strings.Contains(" "+strings.Join(words, " ")+" ", " "+word+" ") {
+					t.Errorf("%s/%s: expected %q not to be a stop word", language, lookup.name, word)
+				}
+			}
+		}
+	}
+
+	// nil lookups built from empty lists contain no words
+	if newPerfectHashStopWords(nil).has("a") || newSortedStopWords(nil).has("a") ||
+		newLengthBucketedStopWords(nil).has("a") || newBloomStopWords(nil).has("a") {
+		t.Errorf("Expected empty lookups to contain no words")
+	}
+	for l := regExpLookup; l <= bloomLookup; l++ {
+		if l.build(nil).has("a") {
+			t.Errorf("Expected empty lookup %d to contain no words", l)
+		}
+	}
+
+	// duplicate words must not prevent the perfect hash table from being built
+	perfect := newPerfectHashStopWords([]string{"the", "a", "the", "of", "a", "the"})
+	for _, word := range []string{"the", "a", "of"} {
+		if !perfect.has(word) {
+			t.Errorf("PerfectHash: expected %q to be a stop word when built with duplicates", word)
+		}
+	}
+	if perfect.has("fox") {
+		t.Errorf("PerfectHash: expected %q not to be a stop word when built with duplicates", "fox")
+	}
+}
+
+// Benchmark the stop word lookup strategies in isolation over every token in the
+// training data
+func BenchmarkStopWordLookup(b *testing.B) {
+	tokeniser := NewRegExpTokeniser()
+	var tokens []string
+	for _, file := range Load("sci.space", "sci.electronics") {
+		tokens = append(tokens, tokeniser.Tokenise(file)...)
+	}
+
+	for _, lookup := range stopWordLookups(EnglishStopWords) {
+		b.Run(lookup.name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				for _, token := range tokens {
+					lookup.has(token)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package nlpbench
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Built-in stop word lists.  Words are in lower case to match the output of the
+// Tokenisers.  Note that RegExpTokeniser and ScannerTokeniser only recognise ASCII word
+// characters so UnicodeTokeniser should be used with lists for languages other than
+// English.
+var (
+	// EnglishStopWords is the default list of stop words removed by the vectorisers.
+	EnglishStopWords = []string{
+		"a", "about", "above", "across", "after", "afterwards", "again", "against", "all",
+		"almost", "alone", "along", "already", "also", "although", "always", "am", "among",
+		"amongst", "amoungst", "amount", "an", "and", "another", "any", "anyhow", "anyone",
+		"anything", "anyway", "anywhere", "are", "around", "as", "at", "back", "be",
+		"became", "because", "become", "becomes", "becoming", "been", "before",
+		"beforehand", "behind", "being", "below", "beside", "besides", "between", "beyond",
+		"bill", "both", "bottom", "but", "by", "call", "can", "cannot", "cant", "co",
+		"con", "could", "couldnt", "cry", "de", "describe", "detail", "do", "done", "down",
+		"due", "during", "each", "eg", "eight", "either", "eleven", "else", "elsewhere",
+		"empty", "enough", "etc", "even", "ever", "every", "everyone", "everything",
+		"everywhere", "except", "few", "fifteen", "fify", "fill", "find", "fire", "first",
+		"five", "for", "former", "formerly", "forty", "found", "four", "from", "front",
+		"full", "further", "get", "give", "go", "had", "has", "hasnt", "have", "he",
+		"hence", "her", "here", "hereafter", "hereby", "herein", "hereupon", "hers",
+		"herself", "him", "himself", "his", "how", "however", "hundred", "ie", "if", "in",
+		"inc", "indeed", "interest", "into", "is", "it", "its", "itself", "keep", "last",
+		"latter", "latterly", "least", "less", "ltd", "made", "many", "may", "me",
+		"meanwhile", "might", "mill", "mine", "more", "moreover", "most", "mostly", "move",
+		"much", "must", "my", "myself", "name", "namely", "neither", "never",
+		"nevertheless", "next", "nine", "no", "nobody", "none", "noone", "nor", "not",
+		"nothing", "now", "nowhere", "of", "off", "often", "on", "once", "one", "only",
+		"onto", "or", "other", "others", "otherwise", "our", "ours", "ourselves", "out",
+		"over", "own", "part", "per", "perhaps", "please", "put", "rather", "re", "same",
+		"see", "seem", "seemed", "seeming", "seems", "serious", "several", "she", "should",
+		"show", "side", "since", "sincere", "six", "sixty", "so", "some", "somehow",
+		"someone", "something", "sometime", "sometimes", "somewhere", "still", "such",
+		"system", "take", "ten", "than", "that", "the", "their", "them", "themselves",
+		"then", "thence", "there", "thereafter", "thereby", "therefore", "therein",
+		"thereupon", "these", "they", "thick", "thin", "third", "this", "those", "though",
+		"three", "through", "throughout", "thru", "thus", "to", "together", "too", "top",
+		"toward", "towards", "twelve", "twenty", "two", "un", "under", "until", "up",
+		"upon", "us", "very", "via", "was", "we", "well", "were", "what", "whatever",
+		"when", "whence", "whenever", "where", "whereafter", "whereas", "whereby",
+		"wherein", "whereupon", "wherever", "whether", "which", "while", "whither", "who",
+		"whoever", "whole", "whom", "whose", "why", "will", "with", "within", "without",
+		"would", "yet", "you", "your", "yours", "yourself", "yourselves",
+	}
+
+	// FrenchStopWords is a list of common French stop words.
+	FrenchStopWords = []string{
+		"au", "aux", "avec", "ce", "ces", "dans", "de", "des", "du", "elle", "en", "et",
+		"eux", "il", "ils", "je", "la", "le", "les", "leur", "lui", "ma", "mais", "me",
+		"même", "mes", "moi", "mon", "ne", "nos", "notre", "nous", "on", "ou", "par",
+		"pas", "pour", "qu", "que", "qui", "sa", "se", "ses", "son", "sur", "ta", "te",
+		"tes", "toi", "ton", "tu", "un", "une", "vos", "votre", "vous", "c", "d", "j", "l",
+		"à", "m", "n", "s", "t", "y", "été", "étée", "étées", "étés", "étant", "étante",
+		"étants", "étantes", "suis", "es", "est", "sommes", "êtes", "sont", "serai",
+		"seras", "sera", "serons", "serez", "seront", "serais", "serait", "serions",
+		"seriez", "seraient", "étais", "était", "étions", "étiez", "étaient", "fus", "fut",
+		"fûmes", "fûtes", "furent", "sois", "soit", "soyons", "soyez", "soient", "fusse",
+		"fusses", "fût", "fussions", "fussiez", "fussent", "ayant", "ayante", "ayantes",
+		"ayants", "eu", "eue", "eues", "eus", "ai", "as", "avons", "avez", "ont", "aurai",
+		"auras", "aura", "aurons", "aurez", "auront", "aurais", "aurait", "aurions",
+		"auriez", "auraient", "avais", "avait", "avions", "aviez", "avaient", "eut",
+		"eûmes", "eûtes", "eurent", "aie", "aies", "ait", "ayons", "ayez", "aient",
+		"eusse", "eusses", "eût", "eussions", "eussiez", "eussent",
+	}
+
+	// GermanStopWords is a list of common German stop words.
+	GermanStopWords = []string{
+		"aber", "alle", "allem", "allen", "aller", "alles", "als", "also", "am", "an",
+		"ander", "andere", "anderem", "anderen", "anderer", "anderes", "anderm", "andern",
+		"anderr", "anders", "auch", "auf", "aus", "bei", "bin", "bis", "bist", "da",
+		"damit", "dann", "der", "den", "des", "dem", "die", "das", "dass", "daß",
+		"derselbe", "derselben", "denselben", "desselben", "demselben", "dieselbe",
+		"dieselben", "dasselbe", "dazu", "dein", "deine", "deinem", "deinen", "deiner",
+		"deines", "denn", "derer", "dessen", "dich", "dir", "du", "dies", "diese",
+		"diesem", "diesen", "dieser", "dieses", "doch", "dort", "durch", "ein", "eine",
+		"einem", "einen", "einer", "eines", "einig", "einige", "einigem", "einigen",
+		"einiger", "einiges", "einmal", "er", "ihn", "ihm", "es", "etwas", "euer", "eure",
+		"eurem", "euren", "eurer", "eures", "für", "gegen", "gewesen", "hab", "habe",
+		"haben", "hat", "hatte", "hatten", "hier", "hin", "hinter", "ich", "mich", "mir",
+		"ihr", "ihre", "ihrem", "ihren", "ihrer", "ihres", "euch", "im", "in", "indem",
+		"ins", "ist", "jede", "jedem", "jeden", "jeder", "jedes", "jene", "jenem", "jenen",
+		"jener", "jenes", "jetzt", "kann", "kein", "keine", "keinem", "keinen", "keiner",
+		"keines", "können", "könnte", "machen", "man", "manche", "manchem", "manchen",
+		"mancher", "manches", "mein", "meine", "meinem", "meinen", "meiner", "meines",
+		"mit", "muss", "musste", "nach", "nicht", "nichts", "noch", "nun", "nur", "ob",
+		"oder", "ohne", "sehr", "sein", "seine", "seinem", "seinen", "seiner", "seines",
+		"selbst", "sich", "sie", "ihnen", "sind", "so", "solche", "solchem", "solchen",
+		"solcher", "solches", "soll", "sollte", "sondern", "sonst", "über", "um", "und",
+		"uns", "unsere", "unserem", "unseren", "unser", "unseres", "unter", "viel", "vom",
+		"von", "vor", "während", "war", "waren", "warst", "was", "weg", "weil", "weiter",
+		"welche", "welchem", "welchen", "welcher", "welches", "wenn", "werde", "werden",
+		"wie", "wieder", "will", "wir", "wird", "wirst", "wo", "wollen", "wollte", "würde",
+		"würden", "zu", "zum", "zur", "zwar", "zwischen",
+	}
+
+	// SpanishStopWords is a list of common Spanish stop words.
+	SpanishStopWords = []string{
+		"de", "la", "que", "el", "en", "y", "a", "los", "del", "se", "las", "por", "un",
+		"para", "con", "no", "una", "su", "al", "lo", "como", "más", "pero", "sus", "le",
+		"ya", "o", "este", "sí", "porque", "esta", "entre", "cuando", "muy", "sin",
+		"sobre", "también", "me", "hasta", "hay", "donde", "quien", "desde", "todo", "nos",
+		"durante", "todos", "uno", "les", "ni", "contra", "otros", "ese", "eso", "ante",
+		"ellos", "e", "esto", "mí", "antes", "algunos", "qué", "unos", "yo", "otro",
+		"otras", "otra", "él", "tanto", "esa", "estos", "mucho", "quienes", "nada",
+		"muchos", "cual", "poco", "ella", "estar", "estas", "algunas", "algo", "nosotros",
+		"mi", "mis", "tú", "te", "ti", "tu", "tus", "ellas", "nosotras", "vosotros",
+		"vosotras", "os", "mío", "mía", "míos", "mías", "tuyo", "tuya", "tuyos", "tuyas",
+		"suyo", "suya", "suyos", "suyas", "nuestro", "nuestra", "nuestros", "nuestras",
+		"vuestro", "vuestra", "vuestros", "vuestras", "esos", "esas", "estoy", "estás",
+		"está", "estamos", "estáis", "están", "esté", "estés", "estemos", "estéis",
+		"estén", "estaré", "estarás", "estará", "estaremos", "estaréis", "estarán",
+		"estaba", "estabas", "estábamos", "estabais", "estaban", "estuve", "estuviste",
+		"estuvo", "estuvimos", "estuvisteis", "estuvieron", "he", "has", "ha", "hemos",
+		"habéis", "han", "haya", "hayas", "hayamos", "hayáis", "hayan", "había", "habías",
+		"habíamos", "habíais", "habían", "hube", "hubo", "hubieron", "soy", "eres", "es",
+		"somos", "sois", "son", "sea", "seas", "seamos", "seáis", "sean", "era", "eras",
+		"éramos", "erais", "eran", "fui", "fue", "fuimos", "fueron", "tengo", "tienes",
+		"tiene", "tenemos", "tenéis", "tienen", "tenía", "tenían", "tuve", "tuvo",
+		"tuvieron",
+	}
+)
+
+// stopWordLists maps language names to the built-in stop word lists.
+var stopWordLists = map[string][]string{
+	"english": EnglishStopWords,
+	"french":  FrenchStopWords,
+	"german":  GermanStopWords,
+	"spanish": SpanishStopWords,
+}
+
+// StopWords returns a copy of the built-in stop word list for the specified language
+// (e.g. "english", "french", "german" or "spanish").  Language names are case
+// insensitive.
+func StopWords(language string) ([]string, error) {
+	words, ok := stopWordLists[strings.ToLower(language)]
+	if !ok {
+		return nil, fmt.Errorf("nlpbench: no stop word list for language %q", language)
+	}
+	return append([]string(nil), words...), nil
+}
+
+// ReadStopWords reads a list of stop words from r, one word per line.  Leading and
+// trailing whitespace is trimmed, words are converted to lower case and blank lines and
+// lines starting with '#' are ignored.
+func ReadStopWords(r io.Reader) ([]string, error) {
+	var words []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		words = append(words, strings.ToLower(word))
+	}
+
+	return words, scanner.Err()
+}
+
+// LoadStopWords reads a list of stop words from the file at path, in the format
+// described for ReadStopWords.
+func LoadStopWords(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadStopWords(f)
+}
+
+// uniqueStopWords returns the non empty words in order with any duplicates removed.
+func uniqueStopWords(words []string) []string {
+	seen := make(map[string]bool, len(words))
+	unique := make([]string, 0, len(words))
+	for _, word := range words {
+		if word == "" || seen[word] {
+			continue
+		}
+		seen[word] = true
+		unique = append(unique, word)
+	}
+	return unique
+}
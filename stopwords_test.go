@@ -0,0 +1,87 @@
+package nlpbench
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+func TestBuiltInStopWords(t *testing.T) {
+	for language, words := range stopWordLists {
+		seen := make(map[string]bool)
+		for _, word := range words {
+			if seen[word] {
+				t.Errorf("Duplicate stop word %q in %s list", word, language)
+			}
+			if word != strings.ToLower(word) {
+				t.Errorf("Stop word %q in %s list is not lower case", word, language)
+			}
+			seen[word] = true
+		}
+
+		got, err := StopWords(strings.Title(language))
+		if err != nil {
+			t.Errorf("Failed to get %s stop words: %v", language, err)
+		}
+		if len(got) != len(words) {
+			t.Errorf("Expected %d %s stop words but got %d", len(words), language, len(got))
+		}
+	}
+
+	if _, err := StopWords("klingon"); err == nil {
+		t.Errorf("Expected error for unknown language but got none")
+	}
+}
+
+func TestReadStopWords(t *testing.T) {
+	words, err := ReadStopWords(strings.NewReader("# custom list\nFox\n\n  dog  \nfox\n"))
+	if err != nil {
+		t.Fatalf("Failed to read stop words: %v", err)
+	}
+
+	want := []string{"fox", "dog", "fox"}
+	if strings.Join(words, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v but got %v", want, words)
+	}
+}
+
+func TestCustomStopWords(t *testing.T) {
+	docs := []string{
+		"The quick brown fox jumped over the lazy dog",
+		"der schnelle braune Fuchs springt über den faulen Hund",
+		"a big red space shuttle launched into orbit above the sun",
+	}
+	stop := []string{"the", "fox", "über", "den", "dog", "fox", "a+b", "sun"}
+
+	// equivalent documents with the stop words removed by hand
+	stripped := make([]string, len(docs))
+	for i, doc := range docs {
+		var words []string
+		for _, word := range (&UnicodeTokeniser{}).Tokenise(doc) {
+			if !strings.Contains(" the fox über den dog sun ", " "+word+" ") {
+				words = append(words, word)
+			}
+		}
+		stripped[i] = strings.Join(words, " ")
+	}
+
+	for _, v := range Vectorisers {
+		t.Run(v.Name, func(t *testing.T) {
+			vect := v.New(false)
+			analyserOf(vect).Tokeniser = &UnicodeTokeniser{}
+			vect.(interface {
+				SetStopWords(words []string)
+			}).SetStopWords(stop)
+			got, _ := vect.FitTransform(docs...)
+
+			plain := v.New(false)
+			analyserOf(plain).Tokeniser = &UnicodeTokeniser{}
+			want, _ := plain.FitTransform(stripped...)
+
+			if !mat64.Equal(got, want) {
+				t.Errorf("Expected\n%v\nbut got\n%v", mat64.Formatted(want), mat64.Formatted(got))
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package nlpbench
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// streamBatchSize is the number of documents read from a DocumentSource and processed
+// at a time by the streaming variants of Fit and Transform.
+const streamBatchSize = 1000
+
+// maxDocumentSize is the maximum size in bytes of a single document read by a
+// ReaderSource.
+const maxDocumentSize = 64 * 1024 * 1024
+
+// StreamFitter is implemented by vectorisers able to fit a stream of documents read
+// from a DocumentSource, rather than requiring all the documents to be held in memory
+// at once.  Only the vocabulary is retained in memory.  StreamFitter is implemented by
+// every vectoriser in this package.
+type StreamFitter interface {
+	Vectoriser
+	FitStream(src DocumentSource) (Vectoriser, error)
+}
+
+// StreamingVectoriser is implemented by vectorisers able to both fit and transform
+// streams of documents read from a DocumentSource.  Only the vocabulary and the sparse
+// output matrix are retained in memory.  StreamingVectoriser is implemented by
+// SparseCountVectoriser, DOKCountVectoriser1 and HashingVectoriser.  CountVectoriser1 to
+// CountVectoriser7 implement only StreamFitter as memory use when transforming would
+// still be dominated by their dense output matrices.
+type StreamingVectoriser interface {
+	StreamFitter
+	TransformStream(src DocumentSource) (mat64.Matrix, error)
+}
+
+// DocumentSource is an iterator over a stream of documents.
+type DocumentSource interface {
+	// Next returns the next document from the source or io.EOF once all documents have
+	// been read.
+	Next() (string, error)
+}
+
+// ChannelSource is a DocumentSource that receives documents from a channel until the
+// channel is closed.
+type ChannelSource <-chan string
+
+// Next receives the next document from the channel or returns io.EOF if the channel is
+// closed.
+func (c ChannelSource) Next() (string, error) {
+	doc, ok := <-c
+	if !ok {
+		return "", io.EOF
+	}
+	return doc, nil
+}
+
+// ReaderSource is a DocumentSource that reads documents from an io.Reader, splitting
+// the input into separate documents using a bufio.SplitFunc.
+type ReaderSource struct {
+	scanner *bufio.Scanner
+}
+
+// NewReaderSource creates a new ReaderSource reading documents from r, delimited using
+// split.  If split is nil, each line of input is treated as a separate document.
+func NewReaderSource(r io.Reader, split bufio.SplitFunc) *ReaderSource {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxDocumentSize)
+	if split != nil {
+		scanner.Split(split)
+	}
+	return &ReaderSource{scanner: scanner}
+}
+
+// Next reads the next document from the underlying io.Reader or returns io.EOF once
+// the end of the input is reached.
+func (s *ReaderSource) Next() (string, error) {
+	if s.scanner.Scan() {
+		return s.scanner.Text(), nil
+	}
+	if err := s.scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+// FileSource is a DocumentSource that reads each of a list of files as a separate
+// document.  Files are only read as each document is requested.
+type FileSource struct {
+	paths []string
+	next  int
+}
+
+// NewFileSource creates a new FileSource reading the files with the specified paths.
+func NewFileSource(paths ...string) *FileSource {
+	return &FileSource{paths: paths}
+}
+
+// Next reads the next file or returns io.EOF once all files have been read.
+func (s *FileSource) Next() (string, error) {
+	if s.next >= len(s.paths) {
+		return "", io.EOF
+	}
+	b, err := ioutil.ReadFile(s.paths[s.next])
+	s.next++
+	return string(b), err
+}
+
+// readBatch reads documents from src into batch until batch is full (reaches its
+// capacity) or src returns an error (including io.EOF), returning the batch and any
+// error.
+func readBatch(src DocumentSource, batch []string) ([]string, error) {
+	batch = batch[:0]
+	for len(batch) < cap(batch) {
+		doc, err := src.Next()
+		if err != nil {
+			return batch, err
+		}
+		batch = append(batch, doc)
+	}
+	return batch, nil
+}
+
+// fitVocabularyStream extends vocab with any new terms found in the training documents
+// read from src, in batches, as for fitVocabulary.  The number of documents read is
+// returned along with any error reading from src.
+func (a *Analyser) fitVocabularyStream(vocab map[string]int, src DocumentSource, isStopWord func(string) bool, freqs *termFrequencies) (int, error) {
+	var n int
+	batch := make([]string, 0, streamBatchSize)
+
+	for {
+		var err error
+		batch, err = readBatch(src, batch)
+
+		a.fitVocabulary(vocab, batch, isStopWord, freqs)
+		n += len(batch)
+		if freqs != nil {
+			freqs.offset = n
+		}
+
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// streamColumns reads batches of documents from src, vectorising each batch into
+// shards of compressed sparse columns using transform, and returns the shards for all
+// the batches in order.
+func streamColumns(src DocumentSource, transform func(docs []string) []*compressedColumns) ([]*compressedColumns, error) {
+	var shards []*compressedColumns
+	batch := make([]string, 0, streamBatchSize)
+
+	for {
+		var err error
+		batch, err = readBatch(src, batch)
+
+		shards = append(shards, transform(batch)...)
+
+		if err == io.EOF {
+			return shards, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package nlpbench
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+func TestStreamingVectoriser(t *testing.T) {
+	words := strings.Fields("the quick brown fox jumped over the lazy dog and then ran away from a big red space shuttle")
+	docs := make([]string, streamBatchSize*2+100)
+	for d := range docs {
+		docs[d] = strings.Join(words[d%7:len(words)-d%5], " ") + fmt.Sprintf(" doc%d", d%1500)
+	}
+
+	sparseVect := func(format SparseFormat, limits VocabularyLimits) func() StreamingVectoriser {
+		return func() StreamingVectoriser {
+			v := NewSparseCountVectoriser(true)
+			v.Format = format
+			v.VocabularyLimits = limits
+			return v
+		}
+	}
+
+	tests := []struct {
+		name string
+		new  func() StreamingVectoriser
+	}{
+		{"SparseCountVectoriserCSR", sparseVect(CSRFormat, VocabularyLimits{})},
+		{"SparseCountVectoriserCSC", sparseVect(CSCFormat, VocabularyLimits{})},
+		{"SparseCountVectoriserLimits", sparseVect(CSRFormat, VocabularyLimits{MinDocs: 2, MaxDFRatio: 0.9})},
+		{"DOKCountVectoriser1", func() StreamingVectoriser { return NewDOKCountVectoriser1(true) }},
+		{"DOKCountVectoriser1Limits", func() StreamingVectoriser {
+			v := NewDOKCountVectoriser1(true)
+			v.VocabularyLimits = VocabularyLimits{MinDocs: 2, MaxDFRatio: 0.9}
+			return v
+		}},
+		{"HashingVectoriser", func() StreamingVectoriser {
+			v, _ := NewHashingVectoriser(true, 1<<10)
+			return v
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want, _ := test.new().FitTransform(docs...)
+
+			ch := make(chan string)
+			go func() {
+				for _, doc := range docs {
+					ch <- doc
+				}
+				close(ch)
+			}()
+
+			vect := test.new()
+			if _, err := vect.FitStream(ChannelSource(ch)); err != nil {
+				t.Fatalf("Failed to fit stream: %v", err)
+			}
+			got, err := vect.TransformStream(NewReaderSource(strings.NewReader(strings.Join(docs, "\n")), nil))
+			if err != nil {
+				t.Fatalf("Failed to transform stream: %v", err)
+			}
+
+			if !mat64.Equal(got, want) {
+				t.Errorf("Streamed matrix does not match matrix from Fit/Transform")
+			}
+		})
+	}
+}
+
+func TestStreamFitter(t *testing.T) {
+	words := strings.Fields("the quick brown fox jumped over the lazy dog and then ran away from a big red space shuttle")
+	docs := make([]string, streamBatchSize+100)
+	for d := range docs {
+		docs[d] = strings.Join(words[d%7:len(words)-d%5], " ") + fmt.Sprintf(" doc%d", d%1500)
+	}
+
+	for _, f := range Vectorisers {
+		t.Run(f.Name, func(t *testing.T) {
+			want, _ := f.New(true).FitTransform(docs...)
+
+			vect, ok := f.New(true).(StreamFitter)
+			if !ok {
+				t.Fatalf("Expected %s to implement StreamFitter", f.Name)
+			}
+			if _, err := vect.FitStream(NewReaderSource(strings.NewReader(strings.Join(docs, "\n")), nil)); err != nil {
+				t.Fatalf("Failed to fit stream: %v", err)
+			}
+			got, err := vect.Transform(docs...)
+			if err != nil {
+				t.Fatalf("Failed to transform: %v", err)
+			}
+
+			if !mat64.Equal(got, want) {
+				t.Errorf("Matrix from FitStream/Transform does not match matrix from Fit/Transform")
+			}
+		})
+	}
+}
+
+// Benchmark streaming files from disk against loading all files into memory first
+
+func BenchmarkSparseCountVectoriserFitTransform(b *testing.B) {
+	vect := NewSparseCountVectoriser(false)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		files := Load("sci.space", "sci.electronics")
+		vect.FitTransform(files...)
+	}
+}
+
+func BenchmarkSparseCountVectoriserFitTransformStream(b *testing.B) {
+	paths := Paths("sci.space", "sci.electronics")
+
+	vect := NewSparseCountVectoriser(false)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		vect.FitStream(NewFileSource(paths...))
+		vect.TransformStream(NewFileSource(paths...))
+	}
+}
@@ -0,0 +1,186 @@
+package nlpbench
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/james-bowman/sparse"
+)
+
+// matrixFormat identifies the storage format of a synthetic term document matrix.
+type matrixFormat int
+
+const (
+	denseMatrix matrixFormat = iota
+	dokMatrix
+	cooMatrix
+	csrMatrix
+	cscMatrix
+)
+
+// matrixFormats lists each of the formats in which synthetic matrices may be generated.
+var matrixFormats = []struct {
+	name   string
+	format matrixFormat
+}{
+	{"Dense", denseMatrix},
+	{"DOK", dokMatrix},
+	{"COO", cooMatrix},
+	{"CSR", csrMatrix},
+	{"CSC", cscMatrix},
+}
+
+const (
+	// syntheticSeed seeds the random source used by the benchmarks so that every run
+	// is measured against the same matrices.
+	syntheticSeed = 42
+
+	// syntheticDensity is the proportion of non zero elements in the matrices used by
+	// the benchmarks, typical of term document matrices built from real text.
+	syntheticDensity = 0.01
+
+	// termZipfS is the exponent of the Zipf distribution from which terms are drawn,
+	// close to the value of 1 observed for natural language.
+	termZipfS = 1.07
+
+	// tfZipfS and maxTf control the Zipf distribution of the frequencies of terms
+	// within a document, the vast majority of which occur only once or twice.
+	tfZipfS = 2
+	maxTf   = 100
+)
+
+// syntheticMatrix returns an m x n term document matrix in the specified format with
+// the proportion density of its elements non zero.  Terms are drawn for each document
+// with Zipfian probabilities, so a few terms occur in most documents and most terms in
+// very few, and the frequency of each term within a document also follows a Zipf
+// distribution.  The same seed always produces the same matrix.  Matrices are not
+// cached, so that large matrices do not remain in the heap during later benchmarks, and
+// so benchmarks should generate them before resetting the timer.
+func syntheticMatrix(m, n int, density float64, seed int64, format matrixFormat) mat64.Matrix {
+	return inFormat(zipfDOK(m, n, density, seed), format)
+}
+
+// inFormat returns the elements of dok as a matrix in the specified format.
+func inFormat(dok *sparse.DOK, format matrixFormat) mat64.Matrix {
+	switch format {
+	case denseMatrix:
+		return dok.ToDense()
+	case cooMatrix:
+		return dok.ToCOO()
+	case csrMatrix:
+		return dok.ToCSR()
+	case cscMatrix:
+		return dok.ToCSC()
+	}
+	return dok
+}
+
+// zipfDOK generates the elements of a synthetic term document matrix as described for
+// syntheticMatrix.
+func zipfDOK(m, n int, density float64, seed int64) *sparse.DOK {
+	dok := sparse.NewDOK(m, n)
+	if m == 0 || n == 0 || density <= 0 {
+		return dok
+	}
+
+	rnd := rand.New(rand.NewSource(seed))
+	terms := rand.NewZipf(rnd, termZipfS, 1, uint64(m-1))
+	tfs := rand.NewZipf(rnd, tfZipfS, 1, maxTf-1)
+
+	// randomly assign terms to rows so that the most frequent terms are not all
+	// clustered at the top of the matrix
+	rows := rnd.Perm(m)
+
+	perDoc := int(density*float64(m) + 0.5)
+	if perDoc < 1 {
+		perDoc = 1
+	}
+	if perDoc > m {
+		perDoc = m
+	}
+
+	seen := make([]bool, m)
+	chosen := make([]int, 0, perDoc)
+
+	for j := 0; j < n; j++ {
+		// draw distinct terms with Zipfian probabilities, falling back to uniform
+		// draws if too many duplicates are drawn so that dense matrices, where most
+		// terms must be chosen, do not take forever to generate
+		for attempts := 0; len(chosen) < perDoc; attempts++ {
+			var i int
+			if attempts < 10*perDoc {
+				i = rows[terms.Uint64()]
+			} else {
+				i = rnd.Intn(m)
+			}
+			if !seen[i] {
+				seen[i] = true
+				chosen = append(chosen, i)
+			}
+		}
+
+		for _, i := range chosen {
+			dok.Set(i, j, float64(1+tfs.Uint64()))
+			seen[i] = false
+		}
+		chosen = chosen[:0]
+	}
+
+	return dok
+}
+
+func TestSyntheticMatrix(t *testing.T) {
+	m, n, density := 500, 40, 0.05
+
+	want := syntheticMatrix(m, n, density, syntheticSeed, denseMatrix)
+	if other := zipfDOK(m, n, density, syntheticSeed); !mat64.Equal(other, want) {
+		t.Errorf("Expected the same seed to generate the same matrix")
+	}
+	if other := zipfDOK(m, n, density, syntheticSeed+1); mat64.Equal(other, want) {
+		t.Errorf("Expected different seeds to generate different matrices")
+	}
+
+	for _, f := range matrixFormats {
+		if got := syntheticMatrix(m, n, density, syntheticSeed, f.format); !mat64.Equal(got, want) {
+			t.Errorf("%s: expected the same matrix in every format", f.name)
+		}
+	}
+
+	df, docLens := documentStats(want)
+	var nnz int
+	for _, d := range df {
+		nnz += d
+	}
+	if nnz != 25*n {
+		t.Errorf("Expected %d non zero elements but got %d", 25*n, nnz)
+	}
+	for j, l := range docLens {
+		if l < 25 {
+			t.Errorf("Expected document %d to contain at least 25 terms but got %v", j, l)
+		}
+	}
+
+	// term frequencies should be Zipfian with most terms occurring once per document
+	var ones int
+	mat64.DenseCopyOf(want).Apply(func(i, j int, v float64) float64 {
+		if v == 1 {
+			ones++
+		}
+		return v
+	}, want)
+	if ones < nnz/2 {
+		t.Errorf("Expected most terms to occur once per document but only %d of %d did", ones, nnz)
+	}
+
+	// and a few terms should occur in far more documents than most
+	var maxDf int
+	for _, d := range df {
+		if d > maxDf {
+			maxDf = d
+		}
+	}
+	if maxDf < n/2 {
+		t.Errorf("Expected the most frequent term to occur in at least %d documents but got %d", n/2, maxDf)
+	}
+}
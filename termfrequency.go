@@ -0,0 +1,198 @@
+package nlpbench
+
+import (
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/james-bowman/sparse"
+)
+
+// TfMode specifies how raw term frequencies (counts) are scaled by the
+// TfTransformer and SparseTfTransformer.  Negative term frequencies, as produced by a
+// HashingVectoriser with AlternateSign, are scaled according to their magnitude and
+// retain their sign.
+type TfMode int
+
+const (
+	// RawTf leaves raw term frequencies unchanged.
+	RawTf TfMode = iota
+
+	// BinaryTf replaces all non zero term frequencies with 1 so that only the presence
+	// or absence of a term within a document is recorded.
+	BinaryTf
+
+	// SublinearTf replaces term frequencies with log(1 + tf) to dampen the effect of
+	// terms that are repeated many times within a document.
+	SublinearTf
+
+	// AugmentedTf replaces non zero term frequencies with 0.5 + 0.5 * tf / max tf, where
+	// max tf is the highest absolute term frequency within the same document, to prevent
+	// a bias towards longer documents.  Terms absent from a document remain zero so that
+	// sparse matrices remain sparse.
+	AugmentedTf
+)
+
+// scale returns the scaled value of the non zero raw term frequency tf, where maxTf is
+// the maximum absolute term frequency within the same document.
+func (mode TfMode) scale(tf float64, maxTf float64) float64 {
+	switch mode {
+	case BinaryTf:
+		return 1
+	case SublinearTf:
+		return math.Copysign(math.Log1p(math.Abs(tf)), tf)
+	case AugmentedTf:
+		if maxTf == 0 {
+			return tf
+		}
+		return math.Copysign(0.5+0.5*math.Abs(tf)/maxTf, tf)
+	}
+	return tf
+}
+
+// nonZeroDoer is implemented by sparse matrices able to iterate over their non zero
+// elements.
+type nonZeroDoer interface {
+	DoNonZero(fn func(i, j int, v float64))
+}
+
+// TfTransformer scales the raw term frequencies of a term document matrix according to
+// Mode.  TfTransformer is stateless, as each document is scaled independently, so Fit is
+// a no-op and is provided to satisfy the Transformer interface.  The transformed matrix
+// is always dense, SparseTfTransformer should be used to retain sparse formats.
+type TfTransformer struct {
+	Mode TfMode
+}
+
+// NewTfTransformer constructs a new TfTransformer scaling term frequencies according to
+// mode.
+func NewTfTransformer(mode TfMode) *TfTransformer {
+	return &TfTransformer{Mode: mode}
+}
+
+// Fit does nothing as there is nothing to fit.
+func (t *TfTransformer) Fit(mat mat64.Matrix) Transformer {
+	return t
+}
+
+// Transform returns a new dense matrix containing the scaled term frequencies of mat.
+func (t *TfTransformer) Transform(mat mat64.Matrix) (*mat64.Dense, error) {
+	m, n := mat.Dims()
+	product := mat64.DenseCopyOf(mat)
+
+	for j := 0; j < n; j++ {
+		var maxTf float64
+		if t.Mode == AugmentedTf {
+			for i := 0; i < m; i++ {
+				maxTf = math.Max(maxTf, math.Abs(product.At(i, j)))
+			}
+		}
+		for i := 0; i < m; i++ {
+			if tf := product.At(i, j); tf != 0 {
+				product.Set(i, j, t.Mode.scale(tf, maxTf))
+			}
+		}
+	}
+
+	return product, nil
+}
+
+// FitTransform is equivalent to calling Fit followed by Transform.
+func (t *TfTransformer) FitTransform(mat mat64.Matrix) (*mat64.Dense, error) {
+	return t.Fit(mat).Transform(mat)
+}
+
+// SparseTfTransformer scales the raw term frequencies of a term document matrix
+// according to Mode, like TfTransformer, but retains the format of sparse input
+// matrices.  CSR and CSC matrices are scaled directly from their underlying arrays into
+// new matrices sharing no storage with the input.  COO matrices, which may contain
+// duplicate entries for the same element, are converted to CSR so that duplicates are
+// summed before scaling.  Other sparse matrices are returned in COO format and dense
+// matrices are returned as dense matrices.
+type SparseTfTransformer struct {
+	Mode TfMode
+}
+
+// NewSparseTfTransformer constructs a new SparseTfTransformer scaling term frequencies
+// according to mode.
+func NewSparseTfTransformer(mode TfMode) *SparseTfTransformer {
+	return &SparseTfTransformer{Mode: mode}
+}
+
+// Fit does nothing as there is nothing to fit.
+func (t *SparseTfTransformer) Fit(mat mat64.Matrix) SparseTransformer {
+	return t
+}
+
+// Transform returns a new matrix containing the scaled term frequencies of mat.
+func (t *SparseTfTransformer) Transform(mat mat64.Matrix) (mat64.Matrix, error) {
+	m, n := mat.Dims()
+
+	switch mat := mat.(type) {
+	case *sparse.CSR:
+		raw := mat.RawMatrix()
+		maxTf := make([]float64, n)
+		if t.Mode == AugmentedTf {
+			for k, j := range raw.Ind {
+				maxTf[j] = math.Max(maxTf[j], math.Abs(raw.Data[k]))
+			}
+		}
+		data := make([]float64, len(raw.Data))
+		for k, j := range raw.Ind {
+			if tf := raw.Data[k]; tf != 0 {
+				data[k] = t.Mode.scale(tf, maxTf[j])
+			}
+		}
+		return sparse.NewCSR(m, n, append([]int(nil), raw.Indptr...), append([]int(nil), raw.Ind...), data), nil
+
+	case *sparse.CSC:
+		raw := mat.RawMatrix()
+		data := make([]float64, len(raw.Data))
+		for j := 0; j < n; j++ {
+			col := raw.Data[raw.Indptr[j]:raw.Indptr[j+1]]
+			var maxTf float64
+			if t.Mode == AugmentedTf {
+				for _, tf := range col {
+					maxTf = math.Max(maxTf, math.Abs(tf))
+				}
+			}
+			for k, tf := range col {
+				if tf != 0 {
+					data[raw.Indptr[j]+k] = t.Mode.scale(tf, maxTf)
+				}
+			}
+		}
+		return sparse.NewCSC(m, n, append([]int(nil), raw.Indptr...), append([]int(nil), raw.Ind...), data), nil
+
+	case *sparse.COO:
+		// COO matrices may contain duplicate entries for the same element which must be
+		// summed before scaling
+		csr, err := t.Transform(mat.ToCSR())
+		if err != nil {
+			return nil, err
+		}
+		return csr.(*sparse.CSR).ToCOO(), nil
+
+	case nonZeroDoer:
+		maxTf := make([]float64, n)
+		if t.Mode == AugmentedTf {
+			mat.DoNonZero(func(i, j int, v float64) {
+				maxTf[j] = math.Max(maxTf[j], math.Abs(v))
+			})
+		}
+		var rows, cols []int
+		var data []float64
+		mat.DoNonZero(func(i, j int, v float64) {
+			rows = append(rows, i)
+			cols = append(cols, j)
+			data = append(data, t.Mode.scale(v, maxTf[j]))
+		})
+		return sparse.NewCOO(m, n, rows, cols, data), nil
+	}
+
+	return (&TfTransformer{Mode: t.Mode}).Transform(mat)
+}
+
+// FitTransform is equivalent to calling Fit followed by Transform.
+func (t *SparseTfTransformer) FitTransform(mat mat64.Matrix) (mat64.Matrix, error) {
+	return t.Fit(mat).Transform(mat)
+}
@@ -0,0 +1,140 @@
+package nlpbench
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/james-bowman/sparse"
+	"github.com/james-bowman/sparse/blas"
+)
+
+func TestTfTransformers(t *testing.T) {
+	// the last document contains only negative values as may be produced by a
+	// HashingVectoriser with AlternateSign
+	counts := mat64.NewDense(3, 4, []float64{
+		1, 0, 4, -2,
+		2, 0, 0, 0,
+		0, 3, 1, -4,
+	})
+
+	tests := []struct {
+		name string
+		mode TfMode
+		want *mat64.Dense
+	}{
+		{"Raw", RawTf, counts},
+		{"Binary", BinaryTf, mat64.NewDense(3, 4, []float64{
+			1, 0, 1, 1,
+			1, 0, 0, 0,
+			0, 1, 1, 1,
+		})},
+		{"Sublinear", SublinearTf, mat64.NewDense(3, 4, []float64{
+			math.Log(2), 0, math.Log(5), -math.Log(3),
+			math.Log(3), 0, 0, 0,
+			0, math.Log(4), math.Log(2), -math.Log(5),
+		})},
+		{"Augmented", AugmentedTf, mat64.NewDense(3, 4, []float64{
+			0.75, 0, 1, -0.75,
+			1, 0, 0, 0,
+			0, 1, 0.625, -1,
+		})},
+	}
+
+	dok := sparse.NewDOK(3, 4)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 4; j++ {
+			if v := counts.At(i, j); v != 0 {
+				dok.Set(i, j, v)
+			}
+		}
+	}
+	// duplicate entries in COO matrices are summed, and may cancel out, before scaling
+	duplicates := sparse.NewCOO(3, 4,
+		[]int{0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2},
+		[]int{0, 2, 2, 3, 0, 0, 1, 1, 1, 2, 3, 3},
+		[]float64{1, 1, 3, -2, 1, 1, 5, -5, 3, 1, -1, -3},
+	)
+	inputs := []mat64.Matrix{counts, dok, dok.ToCSR(), dok.ToCSC(), dok.ToCOO(), duplicates}
+
+	for _, test := range tests {
+		t.Run("Dense/"+test.name, func(t *testing.T) {
+			got, _ := NewTfTransformer(test.mode).FitTransform(counts)
+			if !mat64.EqualApprox(got, test.want, 1e-12) {
+				t.Errorf("Expected\n%v\nbut got\n%v", mat64.Formatted(test.want), mat64.Formatted(got))
+			}
+		})
+
+		for _, input := range inputs {
+			t.Run(fmt.Sprintf("Sparse/%s/%T", test.name, input), func(t *testing.T) {
+				got, _ := NewSparseTfTransformer(test.mode).FitTransform(input)
+
+				wantType := reflect.TypeOf(input)
+				if _, ok := input.(*sparse.DOK); ok {
+					wantType = reflect.TypeOf(&sparse.COO{})
+				}
+				if reflect.TypeOf(got) != wantType {
+					t.Errorf("Expected matrix of type %v but got %T", wantType, got)
+				}
+				if !mat64.EqualApprox(got, test.want, 1e-12) {
+					t.Errorf("Expected\n%v\nbut got\n%v", mat64.Formatted(test.want), mat64.Formatted(got))
+				}
+				if sharesStorage(got, input) {
+					t.Errorf("Expected transformed matrix not to share storage with the input")
+				}
+			})
+		}
+	}
+}
+
+// sharesStorage returns true if the CSR or CSC matrices a and b share any of their
+// underlying arrays, in which case modifying one would corrupt the other.
+func sharesStorage(a, b mat64.Matrix) bool {
+	type rawMatrixer interface {
+		RawMatrix() *blas.SparseMatrix
+	}
+	rawA, okA := a.(rawMatrixer)
+	rawB, okB := b.(rawMatrixer)
+	if !okA || !okB {
+		return false
+	}
+	ra, rb := rawA.RawMatrix(), rawB.RawMatrix()
+	return &ra.Indptr[0] == &rb.Indptr[0] ||
+		(len(ra.Ind) > 0 && len(rb.Ind) > 0 && &ra.Ind[0] == &rb.Ind[0]) ||
+		(len(ra.Data) > 0 && len(rb.Data) > 0 && &ra.Data[0] == &rb.Data[0])
+}
+
+// Benchmark term frequency scaling of dense and sparse matrices
+
+func BenchmarkTfTransform(b *testing.B) {
+	files := Load("sci.space", "sci.electronics")
+	vect := NewSparseCountVectoriser(true)
+	csr, _ := vect.FitTransform(files...)
+	dense := csr.(*sparse.CSR).ToDense()
+
+	modes := []struct {
+		name string
+		mode TfMode
+	}{
+		{"Binary", BinaryTf},
+		{"Sublinear", SublinearTf},
+		{"Augmented", AugmentedTf},
+	}
+
+	for _, m := range modes {
+		b.Run("Dense/"+m.name, func(b *testing.B) {
+			t := NewTfTransformer(m.mode)
+			for n := 0; n < b.N; n++ {
+				t.Transform(dense)
+			}
+		})
+		b.Run("CSR/"+m.name, func(b *testing.B) {
+			t := NewSparseTfTransformer(m.mode)
+			for n := 0; n < b.N; n++ {
+				t.Transform(csr)
+			}
+		})
+	}
+}
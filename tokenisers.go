@@ -0,0 +1,169 @@
+package nlpbench
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Tokeniser is implemented by types that split raw text documents into a sequence of
+// word tokens.  All implementations convert tokens to lower case so that differences
+// in capitalisation do not produce separate terms.
+type Tokeniser interface {
+	Tokenise(text string) []string
+}
+
+// RegExpTokeniser tokenises text using a regular expression to match words.  This is
+// the original tokenisation approach used by all the vectorisers and so serves as the
+// baseline for comparison.
+type RegExpTokeniser struct {
+	wordTokeniser *regexp.Regexp
+}
+
+// NewRegExpTokeniser constructs a new RegExpTokeniser matching whole words (`\w+`).
+func NewRegExpTokeniser() *RegExpTokeniser {
+	return &RegExpTokeniser{
+		wordTokeniser: regexp.MustCompile("\\w+"),
+	}
+}
+
+// Tokenise converts the text to lower case and returns all matches of the regular
+// expression, removing any punctuation/whitespace.
+func (t *RegExpTokeniser) Tokenise(text string) []string {
+	// convert content to lower case
+	c := strings.ToLower(text)
+
+	// match whole words, removing any punctuation/whitespace
+	words := t.wordTokeniser.FindAllString(c, -1)
+
+	return words
+}
+
+// ScannerTokeniser is a hand written tokeniser that scans the text byte by byte rather
+// than using regular expressions.  Words are runs of ASCII letters, digits and
+// underscores (equivalent to the `\w` regular expression class) and all other bytes are
+// treated as separators.
+type ScannerTokeniser struct{}
+
+// Tokenise splits the text into lower case words by scanning for runs of word bytes.
+func (t *ScannerTokeniser) Tokenise(text string) []string {
+	var words []string
+
+	start := -1
+	for i := 0; i < len(text); i++ {
+		if isWordByte(text[i]) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			words = append(words, lowerASCII(text[start:i]))
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, lowerASCII(text[start:]))
+	}
+
+	return words
+}
+
+// InPlaceTokeniser is implemented by Tokenisers able to tokenise a document held in a
+// byte slice in place, without allocating, passing each token to a callback.  Tokens are
+// sub-slices of the text and are only valid until the callback returns.
+type InPlaceTokeniser interface {
+	Tokeniser
+	TokeniseBytes(text []byte, fn func(token []byte))
+}
+
+// ByteTokeniser is an allocation free version of the ScannerTokeniser.  Rather than
+// returning a slice of newly allocated strings, TokeniseBytes converts ASCII upper case
+// letters to lower case in place within the supplied text and yields each token to a
+// callback as a sub-slice of the text.  Words are recognised identically to the
+// ScannerTokeniser.
+type ByteTokeniser struct{}
+
+// Tokenise splits the text into lower case words.  Tokenise allocates the returned words
+// and is provided to satisfy the Tokeniser interface, TokeniseBytes should be used to
+// avoid allocation.
+func (t *ByteTokeniser) Tokenise(text string) []string {
+	var words []string
+	t.TokeniseBytes([]byte(text), func(token []byte) {
+		words = append(words, string(token))
+	})
+	return words
+}
+
+// TokeniseBytes scans the text for runs of word bytes, converting any ASCII upper case
+// letters to lower case in place, and calls fn with each word.
+func (t *ByteTokeniser) TokeniseBytes(text []byte, fn func(token []byte)) {
+	start := -1
+	for i, b := range text {
+		switch {
+		case b >= 'A' && b <= 'Z':
+			text[i] = b + 'a' - 'A'
+		case isWordByte(b):
+		default:
+			if start >= 0 {
+				fn(text[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		fn(text[start:])
+	}
+}
+
+// isWordByte returns true if b is an ASCII letter, digit or underscore.
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '_'
+}
+
+// lowerASCII returns s with all ASCII upper case letters converted to lower case.  If s
+// contains no upper case letters it is returned unchanged without allocating.
+func lowerASCII(s string) string {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 'A' && s[i] <= 'Z' {
+			b := []byte(s)
+			for j := i; j < len(b); j++ {
+				if b[j] >= 'A' && b[j] <= 'Z' {
+					b[j] += 'a' - 'A'
+				}
+			}
+			return string(b)
+		}
+	}
+	return s
+}
+
+// UnicodeTokeniser splits text into words at Unicode word boundaries.  Unlike the
+// RegExpTokeniser and ScannerTokeniser, which only recognise ASCII word characters,
+// words may contain any Unicode letters, marks or digits so non-English words such as
+// `café` are kept intact.
+type UnicodeTokeniser struct{}
+
+// Tokenise converts the text to lower case and splits it into runs of Unicode letters,
+// marks, digits and underscores.
+func (t *UnicodeTokeniser) Tokenise(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), isWordBoundary)
+}
+
+// isWordBoundary returns true if r is not part of a word.
+func isWordBoundary(r rune) bool {
+	return !(unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r) || r == '_')
+}
+
+// WhitespaceTokeniser simply splits text on whitespace.  Punctuation is not removed and
+// so remains attached to words.
+type WhitespaceTokeniser struct{}
+
+// Tokenise converts the text to lower case and splits it on runs of whitespace.
+func (t *WhitespaceTokeniser) Tokenise(text string) []string {
+	return strings.Fields(strings.ToLower(text))
+}
@@ -0,0 +1,184 @@
+package nlpbench
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+func TestByteTokeniser(t *testing.T) {
+	text := "The QUICK brown_fox, jumped over 2 lazy dogs... café Ünïcode-words\n\tEnd"
+
+	want := (&ScannerTokeniser{}).Tokenise(text)
+	got := (&ByteTokeniser{}).Tokenise(text)
+
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected %v but got %v", want, got)
+	}
+}
+
+func TestVectoriserWithByteTokeniser(t *testing.T) {
+	docs := []string{
+		"The quick brown fox jumped over the lazy dog",
+		"THE LAZY DOG SLEPT IN THE SUN WHILE THE FOX RAN AWAY",
+		"a big red space shuttle launched into orbit above the sun",
+	}
+
+	for _, v := range Vectorisers {
+		t.Run(v.Name, func(t *testing.T) {
+			scanner := v.New(true)
+			analyserOf(scanner).Tokeniser = &ScannerTokeniser{}
+			want, _ := scanner.FitTransform(docs...)
+
+			byteVect := v.New(true)
+			analyserOf(byteVect).Tokeniser = &ByteTokeniser{}
+			got, _ := byteVect.FitTransform(docs...)
+
+			if !mat64.Equal(got, want) {
+				t.Errorf("Expected\n%v\nbut got\n%v", mat64.Formatted(want), mat64.Formatted(got))
+			}
+		})
+	}
+}
+
+func TestWithTokeniser(t *testing.T) {
+	tokeniser := &WhitespaceTokeniser{}
+	hashing, _ := NewHashingVectoriser(true, 1<<10, WithTokeniser(tokeniser))
+
+	vects := []struct {
+		name string
+		vect Vectoriser
+	}{
+		{"CountVectoriser1", NewCountVectoriser1(true, WithTokeniser(tokeniser))},
+		{"CountVectoriser2", NewCountVectoriser2(true, WithTokeniser(tokeniser))},
+		{"CountVectoriser3", NewCountVectoriser3(true, WithTokeniser(tokeniser))},
+		{"CountVectoriser4", NewCountVectoriser4(true, WithTokeniser(tokeniser))},
+		{"CountVectoriser5", NewCountVectoriser5(true, WithTokeniser(tokeniser))},
+		{"CountVectoriser6", NewCountVectoriser6(true, WithTokeniser(tokeniser))},
+		{"CountVectoriser7", NewCountVectoriser7(true, WithTokeniser(tokeniser))},
+		{"DOKCountVectoriser1", NewDOKCountVectoriser1(true, WithTokeniser(tokeniser))},
+		{"SparseCountVectoriser", NewSparseCountVectoriser(true, WithTokeniser(tokeniser))},
+		{"HashingVectoriser", hashing},
+	}
+
+	for _, v := range vects {
+		if got := analyserOf(v.vect).Tokeniser; got != tokeniser {
+			t.Errorf("%s: expected Tokeniser %p but got %#v", v.name, tokeniser, got)
+		}
+	}
+
+	// without options the default RegExpTokeniser is used
+	if _, ok := NewCountVectoriser1(true).Tokeniser.(*RegExpTokeniser); !ok {
+		t.Errorf("Expected the default Tokeniser to be a RegExpTokeniser")
+	}
+}
+
+// Benchmark tokenisation algorithms
+
+func benchmarkTokenise(t Tokeniser, b *testing.B) {
+	files := Load()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, doc := range files {
+			t.Tokenise(doc)
+		}
+	}
+}
+
+// Baseline regular expression based tokenisation
+func BenchmarkRegExpTokeniser(b *testing.B) {
+	benchmarkTokenise(NewRegExpTokeniser(), b)
+}
+
+// Hand written byte scanner tokenisation
+func BenchmarkScannerTokeniser(b *testing.B) {
+	benchmarkTokenise(&ScannerTokeniser{}, b)
+}
+
+// Allocation free in place byte tokenisation
+func BenchmarkByteTokeniser(b *testing.B) {
+	files := Load()
+	t := &ByteTokeniser{}
+	var buf []byte
+	var tokens int
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, doc := range files {
+			buf = append(buf[:0], doc...)
+			t.TokeniseBytes(buf, func(token []byte) {
+				tokens++
+			})
+		}
+	}
+}
+
+// Unicode aware word boundary tokenisation
+func BenchmarkUnicodeTokeniser(b *testing.B) {
+	benchmarkTokenise(&UnicodeTokeniser{}, b)
+}
+
+// Whitespace tokenisation
+func BenchmarkWhitespaceTokeniser(b *testing.B) {
+	benchmarkTokenise(&WhitespaceTokeniser{}, b)
+}
+
+// Benchmark the effect of each tokeniser on vectoriser Fit
+func BenchmarkCountVectoriserFitWithTokeniser(b *testing.B) {
+	files := Load()
+
+	tokenisers := []struct {
+		name      string
+		tokeniser Tokeniser
+	}{
+		{"RegExp", NewRegExpTokeniser()},
+		{"Scanner", &ScannerTokeniser{}},
+		{"Byte", &ByteTokeniser{}},
+		{"Unicode", &UnicodeTokeniser{}},
+		{"Whitespace", &WhitespaceTokeniser{}},
+	}
+
+	for _, t := range tokenisers {
+		b.Run(t.name, func(b *testing.B) {
+			vect := NewCountVectoriser2(true)
+			vect.Tokeniser = t.tokeniser
+
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				vect.Fit(files...)
+			}
+		})
+	}
+}
+
+// Benchmark allocations during Transform with the regular expression tokeniser against
+// the allocation free byte tokeniser
+func BenchmarkSparseCountVectoriserTransformWithTokeniser(b *testing.B) {
+	files := Load()
+
+	tokenisers := []struct {
+		name      string
+		tokeniser Tokeniser
+	}{
+		{"RegExp", NewRegExpTokeniser()},
+		{"Byte", &ByteTokeniser{}},
+	}
+
+	for _, t := range tokenisers {
+		b.Run(t.name, func(b *testing.B) {
+			vect := NewSparseCountVectoriser(true)
+			vect.Tokeniser = t.tokeniser
+			vect.Fit(files...)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				vect.Transform(files...)
+			}
+		})
+	}
+}
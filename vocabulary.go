@@ -0,0 +1,223 @@
+package nlpbench
+
+import (
+	"math"
+	"sort"
+)
+
+// VocabularyLimits specifies limits used to prune the vocabulary of a vectoriser after
+// fitting, to exclude terms that are too rare or too common to be useful and to cap the
+// overall size of the vocabulary (and hence the number of rows in the term document
+// matrix).  VocabularyLimits is embedded within the vocabulary based vectorisers and so
+// its fields may be set directly on the vectoriser.  Limits are only applied by Fit and
+// not by PartialFit as document frequencies are not known until all the training data
+// has been seen.  The zero value applies no limits.
+type VocabularyLimits struct {
+	// MinDocs excludes terms occurring in fewer than MinDocs training documents.  Zero
+	// means no lower limit.
+	MinDocs int
+
+	// MaxDocs excludes terms occurring in more than MaxDocs training documents.  Zero
+	// means no upper limit.
+	MaxDocs int
+
+	// MinDFRatio excludes terms occurring in fewer than the proportion MinDFRatio (from 0
+	// to 1) of the training documents.  Zero means no lower limit.
+	MinDFRatio float64
+
+	// MaxDFRatio excludes terms occurring in more than the proportion MaxDFRatio (from 0
+	// to 1) of the training documents so, for example, 1.0 retains terms occurring in
+	// every document.  Zero means no upper limit.
+	MaxDFRatio float64
+
+	// MaxFeatures limits the vocabulary to the MaxFeatures terms with the highest term
+	// frequency across the training documents (after applying the document frequency
+	// limits above).  Zero means no limit.
+	MaxFeatures int
+}
+
+// enabled returns true if any limits are set and so term frequencies need to be
+// tracked during fitting.
+func (l *VocabularyLimits) enabled() bool {
+	return l.MinDocs > 0 || l.MaxDocs > 0 || l.MinDFRatio > 0 || l.MaxDFRatio > 0 || l.MaxFeatures > 0
+}
+
+// prune removes terms from vocab that fall outside the limits, based upon the term
+// frequencies tracked in freqs over nDocs training documents.  The remaining terms are
+// re-indexed densely, preserving their relative order, and returned as a new vocabulary
+// map.  Where both an absolute and a proportional limit are set, the stricter applies.
+func (l *VocabularyLimits) prune(vocab map[string]int, freqs *termFrequencies, nDocs int) map[string]int {
+	minCount := math.Max(float64(l.MinDocs), l.MinDFRatio*float64(nDocs))
+	maxCount := float64(nDocs)
+	if l.MaxDocs > 0 {
+		maxCount = math.Min(maxCount, float64(l.MaxDocs))
+	}
+	if l.MaxDFRatio > 0 {
+		maxCount = math.Min(maxCount, l.MaxDFRatio*float64(nDocs))
+	}
+
+	terms := make([]string, len(vocab))
+	for term, i := range vocab {
+		terms[i] = term
+	}
+
+	var keep []int
+	for i := range terms {
+		df := float64(freqs.df[i])
+		if df >= minCount && df <= maxCount {
+			keep = append(keep, i)
+		}
+	}
+
+	if l.MaxFeatures > 0 && len(keep) > l.MaxFeatures {
+		// retain the most frequent terms, breaking ties by index for repeatability
+		sort.Slice(keep, func(a, b int) bool {
+			tfA, tfB := freqs.tf[keep[a]], freqs.tf[keep[b]]
+			if tfA != tfB {
+				return tfA > tfB
+			}
+			return keep[a] < keep[b]
+		})
+		keep = keep[:l.MaxFeatures]
+		sort.Ints(keep)
+	}
+
+	pruned := make(map[string]int, len(keep))
+	for j, i := range keep {
+		pruned[terms[i]] = j
+	}
+
+	return pruned
+}
+
+// termFrequencies tracks the total number of occurrences (tf) and the number of documents
+// containing (df) each term, indexed by vocabulary index.
+type termFrequencies struct {
+	tf   []int
+	df   []int
+	last []int
+
+	// offset is added to document indices passed to add so that documents may be
+	// added in successive batches with indices starting from 0 in each batch.
+	offset int
+}
+
+// add records an occurrence of the term with the specified vocabulary index within the
+// document with index doc.  Documents must be added in order.
+func (f *termFrequencies) add(term int, doc int) {
+	doc += f.offset
+	for term >= len(f.tf) {
+		f.tf = append(f.tf, 0)
+		f.df = append(f.df, 0)
+		f.last = append(f.last, -1)
+	}
+	f.tf[term]++
+	if f.last[term] != doc {
+		f.df[term]++
+		f.last[term] = doc
+	}
+}
+
+// addCounts adds the specified term and document frequencies to those recorded for the
+// term with the specified vocabulary index.
+func (f *termFrequencies) addCounts(term int, tf int, df int) {
+	for term >= len(f.tf) {
+		f.tf = append(f.tf, 0)
+		f.df = append(f.df, 0)
+		f.last = append(f.last, -1)
+	}
+	f.tf[term] += tf
+	f.df[term] += df
+}
+
+// fitVocabulary extends vocab with any new terms found in the training data (excluding
+// stop words as reported by isStopWord), recording the frequency of each term in freqs
+// if it is not nil.  New terms are assigned indices following on from those already in
+// vocab in order of their first occurrence within the training data.  If Concurrency is
+// 2 or more, the training data is split into shards and a separate vocabulary built
+// concurrently for each shard.  The shard vocabularies are then merged, in order, into
+// vocab so that term indices are identical to those assigned when fitting sequentially.
+func (a *Analyser) fitVocabulary(vocab map[string]int, train []string, isStopWord func(string) bool, freqs *termFrequencies) {
+	checkStopWords := a.checkStopWords()
+
+	// fit extends the vocabulary index with the terms in documents train[start:end]
+	fit := func(index map[string]int, start int, end int, freqs *termFrequencies) {
+		var buf []byte
+		for d := start; d < end; d++ {
+			if t, ok := a.inPlaceTokeniser(); ok {
+				// tokenise in place so that only terms new to the vocabulary are
+				// allocated as strings
+				buf = append(buf[:0], a.normalise(train[d])...)
+				t.TokeniseBytes(buf, func(term []byte) {
+					j, exists := index[string(term)]
+					if !exists {
+						word := string(term)
+						if checkStopWords && isStopWord(word) {
+							return
+						}
+						j = len(index)
+						index[word] = j
+					}
+					if freqs != nil {
+						freqs.add(j, d)
+					}
+				})
+				continue
+			}
+
+			words := a.analyse(train[d], isStopWord)
+
+			for _, word := range words {
+				j, exists := index[word]
+				if !exists {
+					// if enabled, remove stop words
+					if checkStopWords && isStopWord(word) {
+						continue
+					}
+					j = len(index)
+					index[word] = j
+				}
+				if freqs != nil {
+					freqs.add(j, d)
+				}
+			}
+		}
+	}
+
+	shards := a.shards(len(train))
+	if shards == 1 {
+		fit(vocab, 0, len(train), freqs)
+		return
+	}
+
+	indexes := make([]map[string]int, shards)
+	shardFreqs := make([]*termFrequencies, shards)
+
+	a.parallel(len(train), func(shard, start, end int) {
+		indexes[shard] = make(map[string]int)
+		if freqs != nil {
+			shardFreqs[shard] = &termFrequencies{}
+		}
+		fit(indexes[shard], start, end, shardFreqs[shard])
+	})
+
+	// merge the shard vocabularies in order, visiting the terms of each shard in order
+	// of their first occurrence
+	for shard, index := range indexes {
+		terms := make([]string, len(index))
+		for term, j := range index {
+			terms[j] = term
+		}
+
+		for j, term := range terms {
+			i, exists := vocab[term]
+			if !exists {
+				i = len(vocab)
+				vocab[term] = i
+			}
+			if freqs != nil {
+				freqs.addCounts(i, shardFreqs[shard].tf[j], shardFreqs[shard].df[j])
+			}
+		}
+	}
+}
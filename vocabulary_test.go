@@ -0,0 +1,116 @@
+package nlpbench
+
+import (
+	"reflect"
+	"testing"
+)
+
+// limitedVectoriser provides access to the VocabularyLimits and Vocabulary of a
+// vocabulary based Vectoriser.
+type limitedVectoriser struct {
+	Vectoriser
+	limits     *VocabularyLimits
+	vocabulary *map[string]int
+}
+
+func limitedVectorisers() map[string]limitedVectoriser {
+	cv1 := NewCountVectoriser1(false)
+	cv2 := NewCountVectoriser2(false)
+	cv3 := NewCountVectoriser3(false)
+	dok := NewDOKCountVectoriser1(false)
+	csr := NewSparseCountVectoriser(false)
+
+	return map[string]limitedVectoriser{
+		"CountVectoriser1":      {cv1, &cv1.VocabularyLimits, &cv1.Vocabulary},
+		"CountVectoriser2":      {cv2, &cv2.VocabularyLimits, &cv2.Vocabulary},
+		"CountVectoriser3":      {cv3, &cv3.VocabularyLimits, &cv3.Vocabulary},
+		"DOKCountVectoriser1":   {dok, &dok.VocabularyLimits, &dok.Vocabulary},
+		"SparseCountVectoriser": {csr, &csr.VocabularyLimits, &csr.Vocabulary},
+	}
+}
+
+func TestVocabularyLimits(t *testing.T) {
+	docs := []string{
+		"apple banana cherry",
+		"apple banana banana",
+		"apple cherry durian",
+		"apple elderberry elderberry elderberry",
+	}
+
+	tests := []struct {
+		name   string
+		limits VocabularyLimits
+		want   map[string]int
+	}{
+		{
+			name:   "NoLimits",
+			limits: VocabularyLimits{},
+			want:   map[string]int{"apple": 0, "banana": 1, "cherry": 2, "durian": 3, "elderberry": 4},
+		},
+		{
+			name:   "MinDocs",
+			limits: VocabularyLimits{MinDocs: 2},
+			want:   map[string]int{"apple": 0, "banana": 1, "cherry": 2},
+		},
+		{
+			name:   "MaxDFRatio",
+			limits: VocabularyLimits{MaxDFRatio: 0.5},
+			want:   map[string]int{"banana": 0, "cherry": 1, "durian": 2, "elderberry": 3},
+		},
+		{
+			name:   "MaxDocs",
+			limits: VocabularyLimits{MaxDocs: 1},
+			want:   map[string]int{"durian": 0, "elderberry": 1},
+		},
+		{
+			name:   "AllDocuments",
+			limits: VocabularyLimits{MaxDFRatio: 1.0},
+			want:   map[string]int{"apple": 0, "banana": 1, "cherry": 2, "durian": 3, "elderberry": 4},
+		},
+		{
+			name:   "MinDFRatio",
+			limits: VocabularyLimits{MinDFRatio: 0.75},
+			want:   map[string]int{"apple": 0},
+		},
+		{
+			name:   "MaxFeatures",
+			limits: VocabularyLimits{MaxFeatures: 3},
+			want:   map[string]int{"apple": 0, "banana": 1, "elderberry": 2},
+		},
+		{
+			name:   "Combined",
+			limits: VocabularyLimits{MinDFRatio: 0.5, MaxDocs: 3, MaxFeatures: 1},
+			want:   map[string]int{"banana": 0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for name, v := range limitedVectorisers() {
+				*v.limits = test.limits
+				mat, _ := v.FitTransform(docs...)
+
+				if !reflect.DeepEqual(*v.vocabulary, test.want) {
+					t.Errorf("%s: expected vocabulary %v but got %v", name, test.want, *v.vocabulary)
+				}
+				if m, _ := mat.Dims(); m != len(test.want) {
+					t.Errorf("%s: expected %d rows but got %d", name, len(test.want), m)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCountVectoriserFitWithVocabularyLimits(b *testing.B) {
+	files := Load()
+
+	vect := NewCountVectoriser2(true)
+	vect.MinDocs = 2
+	vect.MaxDFRatio = 0.5
+	vect.MaxFeatures = 10000
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		vect.Fit(files...)
+	}
+}
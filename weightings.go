@@ -1,6 +1,7 @@
 package nlpbench
 
 import (
+	"fmt"
 	"math"
 
 	"github.com/gonum/matrix/mat64"
@@ -13,25 +14,121 @@ type Transformer interface {
 	FitTransform(mat mat64.Matrix) (*mat64.Dense, error)
 }
 
+// SparseTransformer is the sibling of Transformer implemented by transformers that may
+// return matrices in sparse formats rather than always returning dense matrices.
+type SparseTransformer interface {
+	Fit(mat64.Matrix) SparseTransformer
+	Transform(mat mat64.Matrix) (mat64.Matrix, error)
+	FitTransform(mat mat64.Matrix) (mat64.Matrix, error)
+}
+
+// AsSparseTransformer adapts the Transformer t to the SparseTransformer interface so
+// that dense and sparse transformers may be used interchangeably.  The adapted
+// transformer continues to return dense matrices.
+func AsSparseTransformer(t Transformer) SparseTransformer {
+	return denseTransformer{t}
+}
+
+// denseTransformer adapts a Transformer to the SparseTransformer interface.
+type denseTransformer struct {
+	Transformer
+}
+
+func (t denseTransformer) Fit(mat mat64.Matrix) SparseTransformer {
+	return denseTransformer{t.Transformer.Fit(mat)}
+}
+
+func (t denseTransformer) Transform(mat mat64.Matrix) (mat64.Matrix, error) {
+	return t.Transformer.Transform(mat)
+}
+
+func (t denseTransformer) FitTransform(mat mat64.Matrix) (mat64.Matrix, error) {
+	return t.Transformer.FitTransform(mat)
+}
+
+// IdfMode specifies the formula used by the tf-idf transformers to calculate the inverse
+// document frequency (idf) of each term, where n is the number of training documents and
+// df the number of those documents containing the term.
+type IdfMode int
+
+const (
+	// DefaultIdf calculates idf as log((1+n)/(1+df)).  1 is added to both n and df to
+	// prevent division by zero.
+	DefaultIdf IdfMode = iota
+
+	// StandardIdf calculates idf as log(n/df).  Terms that occur in no documents are
+	// given an idf of 0.
+	StandardIdf
+
+	// SmoothIdf calculates idf as log((1+n)/(1+df)) + 1, matching scikit-learn's
+	// TfidfTransformer with smooth_idf=True.  Adding 1 ensures that terms occurring in
+	// every document are not ignored entirely.
+	SmoothIdf
+
+	// ProbabilisticIdf calculates idf as log((n-df)/df).  Terms occurring in half or
+	// more of the documents, which would otherwise have a negative idf, and terms that
+	// occur in no documents are given an idf of 0.
+	ProbabilisticIdf
+
+	// MaxIdf calculates idf as log(max df/(1+df)) where max df is the highest document
+	// frequency of any term.  If no term occurs in any document, all terms are given an
+	// idf of 0.
+	MaxIdf
+)
+
+// weights returns the inverse document frequency of each term given the document
+// frequency of each term in df and the number of documents n.
+func (mode IdfMode) weights(df []int, n int) []float64 {
+	var maxDf int
+	if mode == MaxIdf {
+		for _, d := range df {
+			if d > maxDf {
+				maxDf = d
+			}
+		}
+	}
+
+	weights := make([]float64, len(df))
+	for i, d := range df {
+		switch mode {
+		case StandardIdf:
+			if d > 0 {
+				weights[i] = math.Log(float64(n) / float64(d))
+			}
+		case SmoothIdf:
+			weights[i] = math.Log(float64(1+n)/float64(1+d)) + 1
+		case ProbabilisticIdf:
+			if d > 0 && 2*d < n {
+				weights[i] = math.Log(float64(n-d) / float64(d))
+			}
+		case MaxIdf:
+			if maxDf > 0 {
+				weights[i] = math.Log(float64(maxDf) / float64(1+d))
+			}
+		default:
+			weights[i] = math.Log(float64(1+n) / float64(1+d))
+		}
+	}
+
+	return weights
+}
+
 type TfidfTransformer1 struct {
+	// Idf specifies the formula used to calculate inverse document frequencies.
+	Idf IdfMode
+
 	transform *mat64.Dense
 }
 
 func (t *TfidfTransformer1) Fit(mat mat64.Matrix) Transformer {
 	m, n := mat.Dims()
+	df, _ := documentStats(mat)
 
 	// build a diagonal matrix from array of term weighting values for subsequent
 	// multiplication with term document matrics
 	t.transform = mat64.NewDense(m, m, nil)
 
-	for i := 0; i < m; i++ {
-		df := 0
-		for j := 0; j < n; j++ {
-			if mat.At(i, j) != 0 {
-				df++
-			}
-		}
-		idf := math.Log(float64(1+n) / float64(1+df))
+	for i, idf := range t.Idf.weights(df, n) {
 		t.transform.Set(i, i, idf)
 	}
 
@@ -58,10 +155,14 @@ func (t *TfidfTransformer1) FitTransform(mat mat64.Matrix) (*mat64.Dense, error)
 // and so would be weighted down.
 // More precisely, TfidfTransformer applies a tf-idf algorithm to the matrix where each
 // term frequency is multiplied by the inverse document frequency.  Inverse document
-// frequency is calculated as log(n/df) where df is the number of documents in which the
-// term occurs and n is the total number of documents within the corpus.  We add 1 to both n
-// and df before division to prevent division by zero.
+// frequency is calculated from df, the number of documents in which the term occurs, and
+// n, the total number of documents within the corpus, using the formula selected by Idf
+// (see IdfMode).  The zero value, DefaultIdf, calculates log((1+n)/(1+df)), adding 1 to
+// both n and df to prevent division by zero.
 type TfidfTransformer2 struct {
+	// Idf specifies the formula used to calculate inverse document frequencies.
+	Idf IdfMode
+
 	weights []float64
 }
 
@@ -74,20 +175,9 @@ func NewTfidfTransformer() *TfidfTransformer2 {
 // and constructs an inverse document frequency transform to apply to matrices in subsequent
 // calls to Transform().
 func (t *TfidfTransformer2) Fit(mat mat64.Matrix) Transformer {
-	m, n := mat.Dims()
-
-	t.weights = make([]float64, m)
-
-	for i := 0; i < m; i++ {
-		df := 0
-		for j := 0; j < n; j++ {
-			if mat.At(i, j) != 0 {
-				df++
-			}
-		}
-		idf := math.Log(float64(1+n) / float64(1+df))
-		t.weights[i] = idf
-	}
+	_, n := mat.Dims()
+	df, _ := documentStats(mat)
+	t.weights = t.Idf.weights(df, n)
 
 	return t
 }
@@ -115,6 +205,9 @@ func (t *TfidfTransformer2) FitTransform(mat mat64.Matrix) (*mat64.Dense, error)
 }
 
 type TfidfTransformer3 struct {
+	// Idf specifies the formula used to calculate inverse document frequencies.
+	Idf IdfMode
+
 	weights []float64
 }
 
@@ -122,20 +215,9 @@ type TfidfTransformer3 struct {
 // and constructs an inverse document frequency transform to apply to matrices in subsequent
 // calls to Transform().
 func (t *TfidfTransformer3) Fit(mat mat64.Matrix) Transformer {
-	m, n := mat.Dims()
-
-	t.weights = make([]float64, m)
-
-	for i := 0; i < m; i++ {
-		df := 0
-		for j := 0; j < n; j++ {
-			if mat.At(i, j) != 0 {
-				df++
-			}
-		}
-		idf := math.Log(float64(1+n) / float64(1+df))
-		t.weights[i] = idf
-	}
+	_, n := mat.Dims()
+	df, _ := documentStats(mat)
+	t.weights = t.Idf.weights(df, n)
 
 	return t
 }
@@ -161,30 +243,16 @@ func (t *TfidfTransformer3) FitTransform(mat mat64.Matrix) (*mat64.Dense, error)
 }
 
 type SparseTfidfTransformer struct {
+	// Idf specifies the formula used to calculate inverse document frequencies.
+	Idf IdfMode
+
 	transform mat64.Matrix
 }
 
-func (t *SparseTfidfTransformer) Fit(mat mat64.Matrix) *SparseTfidfTransformer {
+func (t *SparseTfidfTransformer) Fit(mat mat64.Matrix) SparseTransformer {
 	m, n := mat.Dims()
-
-	weights := make([]float64, m)
-
-	csr, ok := mat.(*sparse.CSR)
-
-	for i := 0; i < m; i++ {
-		df := 0
-		if ok {
-			df = csr.RowNNZ(i)
-		} else {
-			for j := 0; j < n; j++ {
-				if mat.At(i, j) != 0 {
-					df++
-				}
-			}
-		}
-		idf := math.Log(float64(1+n) / float64(1+df))
-		weights[i] = idf
-	}
+	df, _ := documentStats(mat)
+	weights := t.Idf.weights(df, n)
 
 	// build a diagonal matrix from array of term weighting values for subsequent
 	// multiplication with term document matrics
@@ -194,6 +262,19 @@ func (t *SparseTfidfTransformer) Fit(mat mat64.Matrix) *SparseTfidfTransformer {
 }
 
 func (t *SparseTfidfTransformer) Transform(mat mat64.Matrix) (mat64.Matrix, error) {
+	// scale CSR matrices directly rather than through the general purpose Mul
+	if csr, ok := mat.(*sparse.CSR); ok {
+		product, err := t.TransformTo(nil, csr)
+		if err != nil {
+			return nil, err
+		}
+		return product, nil
+	}
+
+	if _, err := t.weightsFor(mat); err != nil {
+		return nil, err
+	}
+
 	product := &sparse.CSR{}
 
 	// simply multiply the matrix by our idf transform (the diagonal matrix of term weights)
@@ -205,3 +286,300 @@ func (t *SparseTfidfTransformer) Transform(mat mat64.Matrix) (mat64.Matrix, erro
 func (t *SparseTfidfTransformer) FitTransform(mat mat64.Matrix) (mat64.Matrix, error) {
 	return t.Fit(mat).Transform(mat)
 }
+
+// TransformTo applies the fitted tf-idf weights to the CSR matrix mat, storing the
+// result in dst.  The storage of dst is reused where it has sufficient capacity so that
+// repeatedly transforming matrices with a similar number of non zero values into the
+// same dst does not allocate.  If dst is nil, a new CSR matrix is allocated and if dst
+// is mat, mat is transformed in place.  The transformed matrix is returned.
+func (t *SparseTfidfTransformer) TransformTo(dst *sparse.CSR, mat *sparse.CSR) (*sparse.CSR, error) {
+	weights, err := t.weightsFor(mat)
+	if err != nil {
+		return nil, err
+	}
+
+	if dst == nil {
+		dst = &sparse.CSR{}
+	}
+	raw := mat.RawMatrix()
+	if dst != mat {
+		d := dst.RawMatrix()
+		d.I, d.J = raw.I, raw.J
+		d.Indptr = append(d.Indptr[:0], raw.Indptr...)
+		d.Ind = append(d.Ind[:0], raw.Ind...)
+		d.Data = append(d.Data[:0], raw.Data...)
+		raw = d
+	}
+
+	for i, w := range weights {
+		for k := raw.Indptr[i]; k < raw.Indptr[i+1]; k++ {
+			raw.Data[k] *= w
+		}
+	}
+
+	return dst, nil
+}
+
+// TransformInPlace applies the fitted tf-idf weights to the CSR matrix mat by scaling
+// the values in its underlying storage without allocating.  It is equivalent to calling
+// TransformTo(mat, mat).
+func (t *SparseTfidfTransformer) TransformInPlace(mat *sparse.CSR) error {
+	_, err := t.TransformTo(mat, mat)
+	return err
+}
+
+// weightsFor returns the fitted idf weight of each term or an error if the number of
+// terms (rows) of mat does not match the number of terms the transformer was fitted to.
+func (t *SparseTfidfTransformer) weightsFor(mat mat64.Matrix) ([]float64, error) {
+	var weights []float64
+	if dia, ok := t.transform.(*sparse.DIA); ok {
+		weights = dia.Diagonal()
+	}
+	if m, _ := mat.Dims(); m != len(weights) {
+		return nil, fmt.Errorf("nlpbench: matrix has %d terms but transformer was fitted to %d", m, len(weights))
+	}
+	return weights, nil
+}
+
+// BM25 default parameters as commonly used for retrieval.
+const (
+	DefaultBM25K1 = 1.2
+	DefaultBM25B  = 0.75
+)
+
+// bm25Idf returns the BM25 inverse document frequency of a term occurring in df of n
+// documents.  1 is added before taking the logarithm so that terms occurring in more
+// than half of the documents are not given negative weights.
+func bm25Idf(df int, n int) float64 {
+	return math.Log(1 + (float64(n-df)+0.5)/(float64(df)+0.5))
+}
+
+// bm25Weight returns the BM25 weight of a term occurring tf times within a document of
+// length docLen, given the term's idf and the average document length.
+func bm25Weight(tf, idf, docLen, avgDocLen, k1, b float64) float64 {
+	norm := 1 - b
+	if avgDocLen > 0 {
+		norm += b * docLen / avgDocLen
+	}
+	return idf * tf * (k1 + 1) / (tf + k1*norm)
+}
+
+// documentStats returns the document frequency of each term (row) and the length of
+// each document (column sum) of mat.  It is shared by all of the tf-idf and BM25
+// transformers.  For sparse matrices, the statistics are calculated by visiting only
+// the non zero values: CSR and CSC matrices directly from their underlying storage, DIA
+// matrices from their diagonal and DOK matrices by iterating over their elements.  COO
+// matrices may contain duplicate entries for the same element and so are first
+// converted to CSR.  Dense matrices are scanned directly from their backing slice and
+// all other matrices are scanned element by element using At.
+func documentStats(mat mat64.Matrix) ([]int, []float64) {
+	m, n := mat.Dims()
+	df := make([]int, m)
+	docLens := make([]float64, n)
+
+	switch mat := mat.(type) {
+	case *sparse.CSR:
+		raw := mat.RawMatrix()
+		for i := range df {
+			for k := raw.Indptr[i]; k < raw.Indptr[i+1]; k++ {
+				if raw.Data[k] != 0 {
+					df[i]++
+					docLens[raw.Ind[k]] += raw.Data[k]
+				}
+			}
+		}
+	case *sparse.CSC:
+		raw := mat.RawMatrix()
+		for j := range docLens {
+			for k := raw.Indptr[j]; k < raw.Indptr[j+1]; k++ {
+				if raw.Data[k] != 0 {
+					df[raw.Ind[k]]++
+					docLens[j] += raw.Data[k]
+				}
+			}
+		}
+	case *sparse.COO:
+		return documentStats(mat.ToCSR())
+	case *sparse.DIA:
+		for i, v := range mat.Diagonal() {
+			if v != 0 {
+				df[i]++
+				docLens[i] += v
+			}
+		}
+	case nonZeroDoer:
+		mat.DoNonZero(func(i, j int, v float64) {
+			if v != 0 {
+				df[i]++
+				docLens[j] += v
+			}
+		})
+	case *mat64.Dense:
+		raw := mat.RawMatrix()
+		for i := range df {
+			for j, v := range raw.Data[i*raw.Stride : i*raw.Stride+n] {
+				if v != 0 {
+					df[i]++
+					docLens[j] += v
+				}
+			}
+		}
+	default:
+		for i := range df {
+			for j := range docLens {
+				if v := mat.At(i, j); v != 0 {
+					df[i]++
+					docLens[j] += v
+				}
+			}
+		}
+	}
+
+	return df, docLens
+}
+
+// fitBM25 returns the BM25 idf of each term and the average document length of the
+// training term document matrix mat.
+func fitBM25(mat mat64.Matrix) ([]float64, float64) {
+	_, n := mat.Dims()
+	df, docLens := documentStats(mat)
+
+	idf := make([]float64, len(df))
+	for i := range df {
+		idf[i] = bm25Idf(df[i], n)
+	}
+
+	var total float64
+	for _, l := range docLens {
+		total += l
+	}
+	var avgDocLen float64
+	if n > 0 {
+		avgDocLen = total / float64(n)
+	}
+
+	return idf, avgDocLen
+}
+
+// BM25Transformer weights a raw term document matrix using the Okapi BM25 ranking
+// function.  Like tf-idf, each term frequency is weighted by the inverse document
+// frequency of the term but term frequencies saturate, controlled by K1, and are
+// normalised by the length of the document relative to the average document length
+// (captured at Fit time), controlled by B.
+type BM25Transformer struct {
+	// K1 controls term frequency saturation.  Higher values allow repeated terms to
+	// contribute more to the weight.
+	K1 float64
+
+	// B controls document length normalisation from 0 (no normalisation) to 1 (full
+	// normalisation).
+	B float64
+
+	idf       []float64
+	avgDocLen float64
+}
+
+// NewBM25Transformer constructs a new BM25Transformer with the default parameters.
+func NewBM25Transformer() *BM25Transformer {
+	return &BM25Transformer{K1: DefaultBM25K1, B: DefaultBM25B}
+}
+
+// Fit takes a training term document matrix, counts term occurances across all documents
+// and records the inverse document frequency of each term and the average document length
+// to apply to matrices in subsequent calls to Transform().
+func (t *BM25Transformer) Fit(mat mat64.Matrix) Transformer {
+	t.idf, t.avgDocLen = fitBM25(mat)
+	return t
+}
+
+// Transform returns a new dense matrix containing the BM25 weights of mat.
+func (t *BM25Transformer) Transform(mat mat64.Matrix) (*mat64.Dense, error) {
+	m, n := mat.Dims()
+	_, docLens := documentStats(mat)
+	product := mat64.NewDense(m, n, nil)
+
+	product.Apply(func(i, j int, v float64) float64 {
+		if v == 0 {
+			return 0
+		}
+		return bm25Weight(v, t.idf[i], docLens[j], t.avgDocLen, t.K1, t.B)
+	}, mat)
+
+	return product, nil
+}
+
+// FitTransform is exactly equivalent to calling Fit() followed by Transform() on the
+// same matrix.
+func (t *BM25Transformer) FitTransform(mat mat64.Matrix) (*mat64.Dense, error) {
+	return t.Fit(mat).Transform(mat)
+}
+
+// SparseBM25Transformer weights a raw term document matrix using the Okapi BM25
+// ranking function, like BM25Transformer, but retains the format of CSR and CSC input
+// matrices which are weighted directly within copies of their underlying storage.  All
+// other matrices are returned as dense matrices.
+type SparseBM25Transformer struct {
+	// K1 controls term frequency saturation.  Higher values allow repeated terms to
+	// contribute more to the weight.
+	K1 float64
+
+	// B controls document length normalisation from 0 (no normalisation) to 1 (full
+	// normalisation).
+	B float64
+
+	idf       []float64
+	avgDocLen float64
+}
+
+// NewSparseBM25Transformer constructs a new SparseBM25Transformer with the default
+// parameters.
+func NewSparseBM25Transformer() *SparseBM25Transformer {
+	return &SparseBM25Transformer{K1: DefaultBM25K1, B: DefaultBM25B}
+}
+
+// Fit takes a training term document matrix, counts term occurances across all documents
+// and records the inverse document frequency of each term and the average document length
+// to apply to matrices in subsequent calls to Transform().
+func (t *SparseBM25Transformer) Fit(mat mat64.Matrix) SparseTransformer {
+	t.idf, t.avgDocLen = fitBM25(mat)
+	return t
+}
+
+// Transform returns a new matrix containing the BM25 weights of mat.
+func (t *SparseBM25Transformer) Transform(mat mat64.Matrix) (mat64.Matrix, error) {
+	m, n := mat.Dims()
+
+	switch mat := mat.(type) {
+	case *sparse.CSR:
+		_, docLens := documentStats(mat)
+		raw := mat.RawMatrix()
+		data := make([]float64, len(raw.Data))
+		for i := 0; i < m; i++ {
+			for k := raw.Indptr[i]; k < raw.Indptr[i+1]; k++ {
+				j := raw.Ind[k]
+				data[k] = bm25Weight(raw.Data[k], t.idf[i], docLens[j], t.avgDocLen, t.K1, t.B)
+			}
+		}
+		return sparse.NewCSR(m, n, append([]int(nil), raw.Indptr...), append([]int(nil), raw.Ind...), data), nil
+	case *sparse.CSC:
+		_, docLens := documentStats(mat)
+		raw := mat.RawMatrix()
+		data := make([]float64, len(raw.Data))
+		for j := 0; j < n; j++ {
+			for k := raw.Indptr[j]; k < raw.Indptr[j+1]; k++ {
+				i := raw.Ind[k]
+				data[k] = bm25Weight(raw.Data[k], t.idf[i], docLens[j], t.avgDocLen, t.K1, t.B)
+			}
+		}
+		return sparse.NewCSC(m, n, append([]int(nil), raw.Indptr...), append([]int(nil), raw.Ind...), data), nil
+	}
+
+	dense := &BM25Transformer{K1: t.K1, B: t.B, idf: t.idf, avgDocLen: t.avgDocLen}
+	return dense.Transform(mat)
+}
+
+// FitTransform is exactly equivalent to calling Fit() followed by Transform() on the
+// same matrix.
+func (t *SparseBM25Transformer) FitTransform(mat mat64.Matrix) (mat64.Matrix, error) {
+	return t.Fit(mat).Transform(mat)
+}
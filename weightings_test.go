@@ -1,13 +1,28 @@
 package nlpbench
 
 import (
+	"fmt"
+	"math"
+	"reflect"
 	"testing"
 
 	"github.com/gonum/matrix/mat64"
+	"github.com/james-bowman/sparse"
 )
 
-func benchmarkFit(t Transformer, m, n int, b *testing.B) {
-	mat := mat64.NewDense(m, n, nil)
+// benchmarkMatrix returns a synthetic m x n term document matrix in the format suited
+// to t: dense for transformers adapted with AsSparseTransformer and CSR for sparse
+// transformers.
+func benchmarkMatrix(t SparseTransformer, m, n int) mat64.Matrix {
+	format := csrMatrix
+	if _, ok := t.(denseTransformer); ok {
+		format = denseMatrix
+	}
+	return syntheticMatrix(m, n, syntheticDensity, syntheticSeed, format)
+}
+
+func benchmarkFit(t SparseTransformer, m, n int, b *testing.B) {
+	mat := benchmarkMatrix(t, m, n)
 
 	b.ResetTimer()
 	for n := 0; n < b.N; n++ {
@@ -15,8 +30,8 @@ func benchmarkFit(t Transformer, m, n int, b *testing.B) {
 	}
 }
 
-func benchmarkFitTransform(t Transformer, m, n int, b *testing.B) {
-	mat := mat64.NewDense(m, n, nil)
+func benchmarkFitTransform(t SparseTransformer, m, n int, b *testing.B) {
+	mat := benchmarkMatrix(t, m, n)
 
 	b.ResetTimer()
 	for n := 0; n < b.N; n++ {
@@ -24,8 +39,8 @@ func benchmarkFitTransform(t Transformer, m, n int, b *testing.B) {
 	}
 }
 
-func benchmarkTransform(t Transformer, m, n int, b *testing.B) {
-	mat := mat64.NewDense(m, n, nil)
+func benchmarkTransform(t SparseTransformer, m, n int, b *testing.B) {
+	mat := benchmarkMatrix(t, m, n)
 	t.Fit(mat)
 
 	b.ResetTimer()
@@ -35,98 +50,527 @@ func benchmarkTransform(t Transformer, m, n int, b *testing.B) {
 }
 
 func BenchmarkTFIDF1Fit30x3(b *testing.B) {
-	benchmarkFit(&TfidfTransformer1{}, 30, 3, b)
+	benchmarkFit(AsSparseTransformer(&TfidfTransformer1{}), 30, 3, b)
 }
 func BenchmarkTFIDF1Fit300x30(b *testing.B) {
-	benchmarkFit(&TfidfTransformer1{}, 300, 30, b)
+	benchmarkFit(AsSparseTransformer(&TfidfTransformer1{}), 300, 30, b)
 }
 func BenchmarkTFIDF1Fit3000x300(b *testing.B) {
-	benchmarkFit(&TfidfTransformer1{}, 3000, 300, b)
+	benchmarkFit(AsSparseTransformer(&TfidfTransformer1{}), 3000, 300, b)
 }
 func BenchmarkTFIDF1Fit30000x3000(b *testing.B) {
-	benchmarkFit(&TfidfTransformer1{}, 30000, 3000, b)
+	benchmarkFit(AsSparseTransformer(&TfidfTransformer1{}), 30000, 3000, b)
 }
 
 func BenchmarkTFIDF1Transform30x3(b *testing.B) {
-	benchmarkTransform(&TfidfTransformer1{}, 30, 3, b)
+	benchmarkTransform(AsSparseTransformer(&TfidfTransformer1{}), 30, 3, b)
 }
 func BenchmarkTFIDF1Transform300x30(b *testing.B) {
-	benchmarkTransform(&TfidfTransformer1{}, 300, 30, b)
+	benchmarkTransform(AsSparseTransformer(&TfidfTransformer1{}), 300, 30, b)
 }
 func BenchmarkTFIDF1Transform3000x300(b *testing.B) {
-	benchmarkTransform(&TfidfTransformer1{}, 3000, 300, b)
+	benchmarkTransform(AsSparseTransformer(&TfidfTransformer1{}), 3000, 300, b)
 }
 func BenchmarkTFIDF1Transform30000x3000(b *testing.B) {
-	benchmarkTransform(&TfidfTransformer1{}, 30000, 3000, b)
+	benchmarkTransform(AsSparseTransformer(&TfidfTransformer1{}), 30000, 3000, b)
 }
 
 func BenchmarkTFIDF1FitTransform30x3(b *testing.B) {
-	benchmarkFitTransform(&TfidfTransformer1{}, 30, 3, b)
+	benchmarkFitTransform(AsSparseTransformer(&TfidfTransformer1{}), 30, 3, b)
 }
 func BenchmarkTFIDF1FitTransform300x30(b *testing.B) {
-	benchmarkFitTransform(&TfidfTransformer1{}, 300, 30, b)
+	benchmarkFitTransform(AsSparseTransformer(&TfidfTransformer1{}), 300, 30, b)
 }
 func BenchmarkTFIDF1FitTransform3000x300(b *testing.B) {
-	benchmarkFitTransform(&TfidfTransformer1{}, 3000, 300, b)
+	benchmarkFitTransform(AsSparseTransformer(&TfidfTransformer1{}), 3000, 300, b)
 }
 func BenchmarkTFIDF1FitTransform30000x3000(b *testing.B) {
-	benchmarkFitTransform(&TfidfTransformer1{}, 30000, 3000, b)
+	benchmarkFitTransform(AsSparseTransformer(&TfidfTransformer1{}), 30000, 3000, b)
 }
 
 // TFIDF 2
 func BenchmarkTFIDF2Fit30x3(b *testing.B) {
-	benchmarkFit(&TfidfTransformer2{}, 30, 3, b)
+	benchmarkFit(AsSparseTransformer(&TfidfTransformer2{}), 30, 3, b)
 }
 func BenchmarkTFIDF2Fit300x30(b *testing.B) {
-	benchmarkFit(&TfidfTransformer2{}, 300, 30, b)
+	benchmarkFit(AsSparseTransformer(&TfidfTransformer2{}), 300, 30, b)
 }
 func BenchmarkTFIDF2Fit3000x300(b *testing.B) {
-	benchmarkFit(&TfidfTransformer2{}, 3000, 300, b)
+	benchmarkFit(AsSparseTransformer(&TfidfTransformer2{}), 3000, 300, b)
 }
 func BenchmarkTFIDF2Fit30000x3000(b *testing.B) {
-	benchmarkFit(&TfidfTransformer2{}, 30000, 3000, b)
+	benchmarkFit(AsSparseTransformer(&TfidfTransformer2{}), 30000, 3000, b)
 }
 
 func BenchmarkTFIDF2Transform30x3(b *testing.B) {
-	benchmarkTransform(&TfidfTransformer2{}, 30, 3, b)
+	benchmarkTransform(AsSparseTransformer(&TfidfTransformer2{}), 30, 3, b)
 }
 func BenchmarkTFIDF2Transform300x30(b *testing.B) {
-	benchmarkTransform(&TfidfTransformer2{}, 300, 30, b)
+	benchmarkTransform(AsSparseTransformer(&TfidfTransformer2{}), 300, 30, b)
 }
 func BenchmarkTFIDF2Transform3000x300(b *testing.B) {
-	benchmarkTransform(&TfidfTransformer2{}, 3000, 300, b)
+	benchmarkTransform(AsSparseTransformer(&TfidfTransformer2{}), 3000, 300, b)
 }
 func BenchmarkTFIDF2Transform30000x3000(b *testing.B) {
-	benchmarkTransform(&TfidfTransformer2{}, 30000, 3000, b)
+	benchmarkTransform(AsSparseTransformer(&TfidfTransformer2{}), 30000, 3000, b)
 }
 
 func BenchmarkTFIDF2FitTransform30x3(b *testing.B) {
-	benchmarkFitTransform(&TfidfTransformer2{}, 30, 3, b)
+	benchmarkFitTransform(AsSparseTransformer(&TfidfTransformer2{}), 30, 3, b)
 }
 func BenchmarkTFIDF2FitTransform300x30(b *testing.B) {
-	benchmarkFitTransform(&TfidfTransformer2{}, 300, 30, b)
+	benchmarkFitTransform(AsSparseTransformer(&TfidfTransformer2{}), 300, 30, b)
 }
 func BenchmarkTFIDF2FitTransform3000x300(b *testing.B) {
-	benchmarkFitTransform(&TfidfTransformer2{}, 3000, 300, b)
+	benchmarkFitTransform(AsSparseTransformer(&TfidfTransformer2{}), 3000, 300, b)
 }
 func BenchmarkTFIDF2FitTransform30000x3000(b *testing.B) {
-	benchmarkFitTransform(&TfidfTransformer2{}, 30000, 3000, b)
+	benchmarkFitTransform(AsSparseTransformer(&TfidfTransformer2{}), 30000, 3000, b)
 }
 
 // TFIDF 3
 func BenchmarkTFIDF3Transform30x3(b *testing.B) {
-	benchmarkTransform(&TfidfTransformer3{}, 30, 3, b)
+	benchmarkTransform(AsSparseTransformer(&TfidfTransformer3{}), 30, 3, b)
 }
 func BenchmarkTFIDF3Transform300x30(b *testing.B) {
-	benchmarkTransform(&TfidfTransformer3{}, 300, 30, b)
+	benchmarkTransform(AsSparseTransformer(&TfidfTransformer3{}), 300, 30, b)
 }
 func BenchmarkTFIDF3Transform3000x300(b *testing.B) {
-	benchmarkTransform(&TfidfTransformer3{}, 3000, 300, b)
+	benchmarkTransform(AsSparseTransformer(&TfidfTransformer3{}), 3000, 300, b)
 }
 func BenchmarkTFIDF3Transform30000x3000(b *testing.B) {
-	benchmarkTransform(&TfidfTransformer3{}, 30000, 3000, b)
+	benchmarkTransform(AsSparseTransformer(&TfidfTransformer3{}), 30000, 3000, b)
 }
 
 func BenchmarkTFIDF3FitTransform30000x3000(b *testing.B) {
-	benchmarkFitTransform(&TfidfTransformer3{}, 30000, 3000, b)
+	benchmarkFitTransform(AsSparseTransformer(&TfidfTransformer3{}), 30000, 3000, b)
+}
+
+// Sparse TFIDF
+func BenchmarkSparseTFIDFFit30x3(b *testing.B) {
+	benchmarkFit(&SparseTfidfTransformer{}, 30, 3, b)
+}
+func BenchmarkSparseTFIDFFit300x30(b *testing.B) {
+	benchmarkFit(&SparseTfidfTransformer{}, 300, 30, b)
+}
+func BenchmarkSparseTFIDFFit3000x300(b *testing.B) {
+	benchmarkFit(&SparseTfidfTransformer{}, 3000, 300, b)
+}
+func BenchmarkSparseTFIDFFit30000x3000(b *testing.B) {
+	benchmarkFit(&SparseTfidfTransformer{}, 30000, 3000, b)
+}
+
+func BenchmarkSparseTFIDFTransform30x3(b *testing.B) {
+	benchmarkTransform(&SparseTfidfTransformer{}, 30, 3, b)
+}
+func BenchmarkSparseTFIDFTransform300x30(b *testing.B) {
+	benchmarkTransform(&SparseTfidfTransformer{}, 300, 30, b)
+}
+func BenchmarkSparseTFIDFTransform3000x300(b *testing.B) {
+	benchmarkTransform(&SparseTfidfTransformer{}, 3000, 300, b)
+}
+func BenchmarkSparseTFIDFTransform30000x3000(b *testing.B) {
+	benchmarkTransform(&SparseTfidfTransformer{}, 30000, 3000, b)
+}
+
+func BenchmarkSparseTFIDFFitTransform30x3(b *testing.B) {
+	benchmarkFitTransform(&SparseTfidfTransformer{}, 30, 3, b)
+}
+func BenchmarkSparseTFIDFFitTransform300x30(b *testing.B) {
+	benchmarkFitTransform(&SparseTfidfTransformer{}, 300, 30, b)
+}
+func BenchmarkSparseTFIDFFitTransform3000x300(b *testing.B) {
+	benchmarkFitTransform(&SparseTfidfTransformer{}, 3000, 300, b)
+}
+func BenchmarkSparseTFIDFFitTransform30000x3000(b *testing.B) {
+	benchmarkFitTransform(&SparseTfidfTransformer{}, 30000, 3000, b)
+}
+
+func TestIdfModes(t *testing.T) {
+	// document frequencies of the terms are 2, 4 (all documents), 1 and 0
+	counts := mat64.NewDense(4, 4, []float64{
+		1, 0, 2, 0,
+		3, 1, 1, 1,
+		0, 0, 0, 1,
+		0, 0, 0, 0,
+	})
+
+	tests := []struct {
+		name string
+		mode IdfMode
+		idf  []float64
+	}{
+		{"Default", DefaultIdf, []float64{math.Log(5.0 / 3), math.Log(5.0 / 5), math.Log(5.0 / 2), math.Log(5.0 / 1)}},
+		{"Standard", StandardIdf, []float64{math.Log(4.0 / 2), math.Log(4.0 / 4), math.Log(4.0 / 1), 0}},
+		{"Smooth", SmoothIdf, []float64{math.Log(5.0/3) + 1, math.Log(5.0/5) + 1, math.Log(5.0/2) + 1, math.Log(5.0/1) + 1}},
+		{"Probabilistic", ProbabilisticIdf, []float64{0, 0, math.Log(3.0 / 1), 0}},
+		{"Max", MaxIdf, []float64{math.Log(4.0 / 3), math.Log(4.0 / 5), math.Log(4.0 / 2), math.Log(4.0 / 1)}},
+	}
+
+	// all empty documents to check no weights are infinite and so the transformed
+	// matrices contain no NaN values
+	empty := mat64.NewDense(4, 4, nil)
+	for _, test := range tests {
+		weights := test.mode.weights([]int{0, 0, 0, 0}, 4)
+		for _, w := range weights {
+			if math.IsInf(w, 0) || math.IsNaN(w) {
+				t.Errorf("%s/Empty: expected finite weights but got %v", test.name, weights)
+				break
+			}
+		}
+		got, _ := (&TfidfTransformer2{Idf: test.mode}).FitTransform(empty)
+		if !mat64.Equal(got, empty) {
+			t.Errorf("%s/Empty: expected\n%v\nbut got\n%v", test.name, mat64.Formatted(empty), mat64.Formatted(got))
+		}
+	}
+
+	dok := sparse.NewDOK(4, 4)
+	counts.Apply(func(i, j int, v float64) float64 {
+		if v != 0 {
+			dok.Set(i, j, v)
+		}
+		return v
+	}, counts)
+
+	for _, test := range tests {
+		want := mat64.DenseCopyOf(counts)
+		want.Apply(func(i, j int, v float64) float64 {
+			return v * test.idf[i]
+		}, want)
+
+		transformers := []struct {
+			name string
+			t    SparseTransformer
+		}{
+			{"TFIDF1", AsSparseTransformer(&TfidfTransformer1{Idf: test.mode})},
+			{"TFIDF2", AsSparseTransformer(&TfidfTransformer2{Idf: test.mode})},
+			{"TFIDF3", AsSparseTransformer(&TfidfTransformer3{Idf: test.mode})},
+			{"Sparse", &SparseTfidfTransformer{Idf: test.mode}},
+		}
+		for _, tr := range transformers {
+			for _, input := range []mat64.Matrix{counts, dok, dok.ToCSR(), dok.ToCSC()} {
+				got, _ := tr.t.FitTransform(input)
+				if !mat64.EqualApprox(got, want, 1e-12) {
+					t.Errorf("%s/%s %T: expected\n%v\nbut got\n%v", test.name, tr.name, input, mat64.Formatted(want), mat64.Formatted(got))
+				}
+			}
+		}
+	}
+}
+
+// elementMatrix hides the concrete type of a matrix so that only its At method may be
+// used to access elements.
+type elementMatrix struct {
+	mat64.Matrix
+}
+
+// documentStatsInputs returns mat in each of the supported input formats.
+func documentStatsInputs(mat *mat64.Dense) []mat64.Matrix {
+	m, n := mat.Dims()
+	dok := sparse.NewDOK(m, n)
+	mat.Apply(func(i, j int, v float64) float64 {
+		if v != 0 {
+			dok.Set(i, j, v)
+		}
+		return v
+	}, mat)
+
+	return []mat64.Matrix{mat, dok, dok.ToCOO(), dok.ToCSR(), dok.ToCSC(), elementMatrix{mat}}
+}
+
+func TestDocumentStats(t *testing.T) {
+	counts := mat64.NewDense(3, 4, []float64{
+		1, 0, 2, 0,
+		3, 1, 0, 0,
+		0, 0, 0, 0,
+	})
+	wantDf := []int{2, 2, 0}
+	wantLens := []float64{4, 1, 2, 0}
+
+	// duplicate entries in COO matrices are summed rather than counted twice
+	coo := sparse.NewCOO(3, 4, []int{0, 0, 0, 1, 1}, []int{0, 2, 2, 0, 1}, []float64{1, 1, 1, 3, 1})
+
+	for _, input := range append(documentStatsInputs(counts), coo) {
+		df, lens := documentStats(input)
+		if !reflect.DeepEqual(df, wantDf) || !reflect.DeepEqual(lens, wantLens) {
+			t.Errorf("%T: expected df %v and lengths %v but got %v and %v", input, wantDf, wantLens, df, lens)
+		}
+	}
+
+	df, lens := documentStats(sparse.NewDIA(3, []float64{2, 0, 1}))
+	if !reflect.DeepEqual(df, []int{1, 0, 1}) || !reflect.DeepEqual(lens, []float64{2, 0, 1}) {
+		t.Errorf("DIA: expected df [1 0 1] and lengths [2 0 1] but got %v and %v", df, lens)
+	}
+}
+
+// Benchmark fitting tf-idf weights, dominated by counting document frequencies, for
+// each input matrix format
+func BenchmarkTfidfFitInputFormats(b *testing.B) {
+	files := Load("sci.space", "sci.electronics")
+	vect := NewSparseCountVectoriser(true)
+	mat, _ := vect.FitTransform(files...)
+	csr := mat.(*sparse.CSR)
+	m, _ := csr.Dims()
+
+	inputs := []struct {
+		name string
+		mat  mat64.Matrix
+	}{
+		{"Dense", csr.ToDense()},
+		{"DOK", csr.ToCOO().ToDOK()},
+		{"COO", csr.ToCOO()},
+		{"CSR", csr},
+		{"CSC", csr.ToCSC()},
+		{"DIA", sparse.NewDIA(m, make([]float64, m))},
+		{"At", elementMatrix{csr}},
+	}
+
+	for _, input := range inputs {
+		b.Run(input.name, func(b *testing.B) {
+			t := &SparseTfidfTransformer{}
+			for n := 0; n < b.N; n++ {
+				t.Fit(input.mat)
+			}
+		})
+	}
+}
+
+// Benchmark fitting tf-idf weights to synthetic matrices of increasing size in each
+// input matrix format
+func BenchmarkTfidfFitSyntheticFormats(b *testing.B) {
+	sizes := []struct{ m, n int }{{300, 30}, {3000, 300}, {30000, 3000}}
+
+	for _, size := range sizes {
+		for _, f := range matrixFormats {
+			b.Run(fmt.Sprintf("%s/%dx%d", f.name, size.m, size.n), func(b *testing.B) {
+				mat := syntheticMatrix(size.m, size.n, syntheticDensity, syntheticSeed, f.format)
+				t := &SparseTfidfTransformer{}
+
+				b.ResetTimer()
+				for n := 0; n < b.N; n++ {
+					t.Fit(mat)
+				}
+			})
+		}
+	}
+}
+
+func TestSparseTfidfTransformInPlace(t *testing.T) {
+	counts := mat64.NewDense(3, 4, []float64{
+		1, 0, 2, 0,
+		3, 1, 0, 0,
+		0, 1, 1, 4,
+	})
+	dok := sparse.NewDOK(3, 4)
+	counts.Apply(func(i, j int, v float64) float64 {
+		if v != 0 {
+			dok.Set(i, j, v)
+		}
+		return v
+	}, counts)
+
+	trans := &SparseTfidfTransformer{}
+	trans.Fit(counts)
+	want, _ := trans.Transform(dok.ToCOO())
+
+	got, err := trans.TransformTo(nil, dok.ToCSR())
+	if err != nil || !mat64.EqualApprox(got, want, 1e-12) {
+		t.Errorf("TransformTo(nil): expected\n%v\nbut got\n%v (%v)", mat64.Formatted(want), mat64.Formatted(got), err)
+	}
+
+	// reuse the previous result as the destination for the next transform
+	csr := dok.ToCSR()
+	got, err = trans.TransformTo(got, csr)
+	if err != nil || !mat64.EqualApprox(got, want, 1e-12) {
+		t.Errorf("TransformTo(dst): expected\n%v\nbut got\n%v (%v)", mat64.Formatted(want), mat64.Formatted(got), err)
+	}
+	if !mat64.EqualApprox(csr, counts, 1e-12) {
+		t.Errorf("TransformTo(dst): expected source matrix to be unchanged but got\n%v", mat64.Formatted(csr))
+	}
+
+	if err := trans.TransformInPlace(csr); err != nil || !mat64.EqualApprox(csr, want, 1e-12) {
+		t.Errorf("TransformInPlace: expected\n%v\nbut got\n%v (%v)", mat64.Formatted(want), mat64.Formatted(csr), err)
+	}
+
+	if err := trans.TransformInPlace(sparse.NewCSR(2, 4, make([]int, 3), []int{}, []float64{})); err == nil {
+		t.Errorf("Expected error transforming matrix with the wrong number of terms but got none")
+	}
+
+	// Transform should return an untyped nil matrix with the error for every format
+	for _, wrong := range []mat64.Matrix{
+		sparse.NewCSR(2, 4, make([]int, 3), []int{}, []float64{}),
+		sparse.NewDOK(2, 4),
+		mat64.NewDense(2, 4, nil),
+	} {
+		if got, err := trans.Transform(wrong); err == nil || got != nil {
+			t.Errorf("%T: expected nil matrix and error transforming matrix with the wrong number of terms but got %#v (%v)", wrong, got, err)
+		}
+	}
+}
+
+// Benchmark applying tf-idf weights to a CSR matrix through the general purpose Mul
+// compared with scaling the values directly into new, reused or the same storage
+func BenchmarkSparseTfidfTransformCSR(b *testing.B) {
+	files := Load("sci.space", "sci.electronics")
+	vect := NewSparseCountVectoriser(true)
+	mat, _ := vect.FitTransform(files...)
+	csr := mat.(*sparse.CSR)
+
+	trans := &SparseTfidfTransformer{}
+	trans.Fit(csr)
+
+	b.Run("Mul", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			product := &sparse.CSR{}
+			product.Mul(trans.transform, csr)
+		}
+	})
+	b.Run("Transform", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			trans.Transform(csr)
+		}
+	})
+	b.Run("TransformTo", func(b *testing.B) {
+		dst, _ := trans.TransformTo(nil, csr)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			trans.TransformTo(dst, csr)
+		}
+	})
+	b.Run("TransformInPlace", func(b *testing.B) {
+		scratch, _ := trans.TransformTo(nil, csr)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			trans.TransformInPlace(scratch)
+		}
+	})
+}
+
+func TestBM25Transformers(t *testing.T) {
+	counts := mat64.NewDense(3, 4, []float64{
+		1, 0, 2, 0,
+		3, 1, 0, 0,
+		0, 1, 1, 4,
+	})
+
+	// documents lengths are 4, 2, 3 and 4 giving an average of 3.25
+	k1, b, avgDocLen := 1.2, 0.75, 3.25
+	idf := func(df float64) float64 {
+		return math.Log(1 + (4-df+0.5)/(df+0.5))
+	}
+	weight := func(tf, df, docLen float64) float64 {
+		return idf(df) * tf * (k1 + 1) / (tf + k1*(1-b+b*docLen/avgDocLen))
+	}
+	want := mat64.NewDense(3, 4, []float64{
+		weight(1, 2, 4), 0, weight(2, 2, 3), 0,
+		weight(3, 2, 4), weight(1, 2, 2), 0, 0,
+		0, weight(1, 3, 2), weight(1, 3, 3), weight(4, 3, 4),
+	})
+
+	got, _ := NewBM25Transformer().FitTransform(counts)
+	if !mat64.EqualApprox(got, want, 1e-12) {
+		t.Errorf("Dense: expected\n%v\nbut got\n%v", mat64.Formatted(want), mat64.Formatted(got))
+	}
+
+	dok := sparse.NewDOK(3, 4)
+	counts.Apply(func(i, j int, v float64) float64 {
+		if v != 0 {
+			dok.Set(i, j, v)
+		}
+		return v
+	}, counts)
+
+	for _, input := range []mat64.Matrix{counts, dok, dok.ToCSR(), dok.ToCSC()} {
+		got, _ := NewSparseBM25Transformer().FitTransform(input)
+		if !mat64.EqualApprox(got, want, 1e-12) {
+			t.Errorf("Sparse %T: expected\n%v\nbut got\n%v", input, mat64.Formatted(want), mat64.Formatted(got))
+		}
+		if sharesStorage(got, input) {
+			t.Errorf("Sparse %T: expected weighted matrix not to share storage with the input", input)
+		}
+	}
+}
+
+// BM25
+func BenchmarkBM25Fit30x3(b *testing.B) {
+	benchmarkFit(AsSparseTransformer(NewBM25Transformer()), 30, 3, b)
+}
+func BenchmarkBM25Fit300x30(b *testing.B) {
+	benchmarkFit(AsSparseTransformer(NewBM25Transformer()), 300, 30, b)
+}
+func BenchmarkBM25Fit3000x300(b *testing.B) {
+	benchmarkFit(AsSparseTransformer(NewBM25Transformer()), 3000, 300, b)
+}
+func BenchmarkBM25Fit30000x3000(b *testing.B) {
+	benchmarkFit(AsSparseTransformer(NewBM25Transformer()), 30000, 3000, b)
+}
+
+func BenchmarkBM25Transform30x3(b *testing.B) {
+	benchmarkTransform(AsSparseTransformer(NewBM25Transformer()), 30, 3, b)
+}
+func BenchmarkBM25Transform300x30(b *testing.B) {
+	benchmarkTransform(AsSparseTransformer(NewBM25Transformer()), 300, 30, b)
+}
+func BenchmarkBM25Transform3000x300(b *testing.B) {
+	benchmarkTransform(AsSparseTransformer(NewBM25Transformer()), 3000, 300, b)
+}
+func BenchmarkBM25Transform30000x3000(b *testing.B) {
+	benchmarkTransform(AsSparseTransformer(NewBM25Transformer()), 30000, 3000, b)
+}
+
+func BenchmarkBM25FitTransform30x3(b *testing.B) {
+	benchmarkFitTransform(AsSparseTransformer(NewBM25Transformer()), 30, 3, b)
+}
+func BenchmarkBM25FitTransform300x30(b *testing.B) {
+	benchmarkFitTransform(AsSparseTransformer(NewBM25Transformer()), 300, 30, b)
+}
+func BenchmarkBM25FitTransform3000x300(b *testing.B) {
+	benchmarkFitTransform(AsSparseTransformer(NewBM25Transformer()), 3000, 300, b)
+}
+func BenchmarkBM25FitTransform30000x3000(b *testing.B) {
+	benchmarkFitTransform(AsSparseTransformer(NewBM25Transformer()), 30000, 3000, b)
+}
+
+// Sparse BM25
+func BenchmarkSparseBM25Fit30x3(b *testing.B) {
+	benchmarkFit(NewSparseBM25Transformer(), 30, 3, b)
+}
+func BenchmarkSparseBM25Fit300x30(b *testing.B) {
+	benchmarkFit(NewSparseBM25Transformer(), 300, 30, b)
+}
+func BenchmarkSparseBM25Fit3000x300(b *testing.B) {
+	benchmarkFit(NewSparseBM25Transformer(), 3000, 300, b)
+}
+func BenchmarkSparseBM25Fit30000x3000(b *testing.B) {
+	benchmarkFit(NewSparseBM25Transformer(), 30000, 3000, b)
+}
+
+func BenchmarkSparseBM25Transform30x3(b *testing.B) {
+	benchmarkTransform(NewSparseBM25Transformer(), 30, 3, b)
+}
+func BenchmarkSparseBM25Transform300x30(b *testing.B) {
+	benchmarkTransform(NewSparseBM25Transformer(), 300, 30, b)
+}
+func BenchmarkSparseBM25Transform3000x300(b *testing.B) {
+	benchmarkTransform(NewSparseBM25Transformer(), 3000, 300, b)
+}
+func BenchmarkSparseBM25Transform30000x3000(b *testing.B) {
+	benchmarkTransform(NewSparseBM25Transformer(), 30000, 3000, b)
+}
+
+func BenchmarkSparseBM25FitTransform30x3(b *testing.B) {
+	benchmarkFitTransform(NewSparseBM25Transformer(), 30, 3, b)
+}
+func BenchmarkSparseBM25FitTransform300x30(b *testing.B) {
+	benchmarkFitTransform(NewSparseBM25Transformer(), 300, 30, b)
+}
+func BenchmarkSparseBM25FitTransform3000x300(b *testing.B) {
+	benchmarkFitTransform(NewSparseBM25Transformer(), 3000, 300, b)
+}
+func BenchmarkSparseBM25FitTransform30000x3000(b *testing.B) {
+	benchmarkFitTransform(NewSparseBM25Transformer(), 30000, 3000, b)
 }